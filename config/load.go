@@ -0,0 +1,475 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// FieldError reports an invalid value for one config key.
+type FieldError struct {
+	Key    string
+	Value  any
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s (got %v)", e.Key, e.Reason, e.Value)
+}
+
+// Load builds a MarkdownConfig from the defaults overridden by the values
+// get returns, coercing JSON and string encodings to each field's type,
+// then validates it. Every invalid key is reported in the joined error.
+func Load(get func(key string) (any, bool)) (*MarkdownConfig, error) {
+	cfg := DefaultConfig()
+	l := &loader{get: get}
+
+	l.bool("enabled", &cfg.Enabled)
+	l.bool("sanitize_html", &cfg.SanitizeHTML)
+	l.bool("allow_inline_styles", &cfg.AllowInlineStyles)
+	l.bool("harden_external_links", &cfg.HardenExternalLinks)
+	l.bool("external_links_new_tab", &cfg.ExternalLinksNewTab)
+	l.bool("enable_mermaid", &cfg.EnableMermaid)
+	l.bool("enable_math", &cfg.EnableMath)
+	l.bool("enable_table_of_contents", &cfg.EnableTableOfContents)
+	l.int("max_input_size", &cfg.MaxInputSize)
+	l.int("render_timeout_ms", &cfg.RenderTimeoutMs)
+	l.int("cache_size", &cfg.CacheSize)
+	l.int("cache_ttl_seconds", &cfg.CacheTTLSeconds)
+	l.int("cache_max_bytes", &cfg.CacheMaxBytes)
+	l.int("batch_workers", &cfg.BatchWorkers)
+	l.int("max_batch_size", &cfg.MaxBatchSize)
+	l.int("max_streams", &cfg.MaxStreams)
+	l.int("max_stream_size", &cfg.MaxStreamSize)
+	l.int("stream_timeout_ms", &cfg.StreamTimeoutMs)
+	l.int("max_nesting_depth", &cfg.MaxNestingDepth)
+	l.int("max_nodes", &cfg.MaxNodes)
+	l.int("max_links", &cfg.MaxLinks)
+	l.int("max_table_cells", &cfg.MaxTableCells)
+	l.int("max_code_block_size", &cfg.MaxCodeBlockSize)
+	l.string("code_theme", &cfg.CodeTheme)
+	l.string("id_prefix", &cfg.IDPrefix)
+	l.string("raw_html", &cfg.RawHTML)
+	l.strings("trusted_hosts", &cfg.TrustedHosts)
+	l.string("workspace_root", &cfg.WorkspaceRoot)
+	l.strings("workspace_extensions", &cfg.WorkspaceExtensions)
+	l.stringMap("resource_roots", &cfg.ResourceRoots)
+
+	raw, _ := get("profiles")
+	profiles, errs := resolveProfiles(cfg, raw)
+	cfg.Profiles = profiles
+	l.errs = append(l.errs, errs...)
+
+	// Keys that failed to load keep their defaults, so validating
+	// anyway reports range problems without duplicating type errors.
+	if err := errors.Join(append(l.errs, cfg.Validate())...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+var (
+	// idPrefixRe restricts id_prefix to characters valid in an HTML id.
+	idPrefixRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	// nameRe restricts profile and resource root names.
+	nameRe = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+)
+
+// resolveProfiles builds every profile from the top-level settings, the
+// built-in overrides, and the configured overrides in raw, a map from
+// profile name to settings.
+func resolveProfiles(cfg *MarkdownConfig, raw any) (map[string]ProfileConfig, []error) {
+	overrides, errs := profileOverrides(raw)
+
+	names := make(map[string]bool)
+	for name := range builtinProfiles {
+		names[name] = true
+	}
+	for name := range overrides {
+		names[name] = true
+	}
+
+	profiles := make(map[string]ProfileConfig, len(names))
+	for name := range names {
+		pc := cfg.DefaultProfile()
+		l := &loader{prefix: "profiles." + name + "."}
+		for _, layer := range []map[string]any{builtinProfiles[name], overrides[name]} {
+			l.get = func(key string) (any, bool) {
+				v, ok := layer[key]
+				return v, ok
+			}
+			l.profile(&pc)
+		}
+		errs = append(errs, l.errs...)
+		profiles[name] = pc
+	}
+	return profiles, errs
+}
+
+// profileOverrides decodes the profiles config value.
+func profileOverrides(raw any) (map[string]map[string]any, []error) {
+	var errs []error
+	out := make(map[string]map[string]any)
+	add := func(name string, v any) {
+		m, ok := v.(map[string]any)
+		switch {
+		case !nameRe.MatchString(name):
+			errs = append(errs, &FieldError{Key: "profiles", Value: name, Reason: "profile names must be lowercase letters, digits, '-' and '_'"})
+		case !ok:
+			errs = append(errs, &FieldError{Key: "profiles." + name, Value: v, Reason: "must be an object"})
+		default:
+			out[name] = m
+		}
+	}
+
+	switch profiles := raw.(type) {
+	case nil:
+	case map[string]any:
+		for name, v := range profiles {
+			add(name, v)
+		}
+	case map[string]map[string]any:
+		for name, v := range profiles {
+			add(name, v)
+		}
+	default:
+		errs = append(errs, &FieldError{Key: "profiles", Value: raw, Reason: "must be an object of profiles"})
+	}
+	return out, errs
+}
+
+// Validate checks every field is in range, returning the problems joined.
+func (c *MarkdownConfig) Validate() error {
+	var errs []error
+	fail := func(key string, value any, reason string) {
+		errs = append(errs, &FieldError{Key: key, Value: value, Reason: reason})
+	}
+
+	for _, f := range []struct {
+		key      string
+		value    int
+		min, max int
+	}{
+		{"max_input_size", c.MaxInputSize, 0, 64 << 20},
+		{"render_timeout_ms", c.RenderTimeoutMs, 0, 600000},
+		{"cache_size", c.CacheSize, 0, 1 << 20},
+		{"cache_ttl_seconds", c.CacheTTLSeconds, 0, 86400 * 7},
+		{"cache_max_bytes", c.CacheMaxBytes, 0, min(16<<30, math.MaxInt)},
+		{"batch_workers", c.BatchWorkers, 1, 256},
+		{"max_batch_size", c.MaxBatchSize, 0, 10000},
+		{"max_streams", c.MaxStreams, 0, 100000},
+		{"max_stream_size", c.MaxStreamSize, 0, 1 << 30},
+		{"stream_timeout_ms", c.StreamTimeoutMs, 0, 3600000},
+		{"max_nesting_depth", c.MaxNestingDepth, 0, 10000},
+		{"max_nodes", c.MaxNodes, 0, math.MaxInt32},
+		{"max_links", c.MaxLinks, 0, math.MaxInt32},
+		{"max_table_cells", c.MaxTableCells, 0, math.MaxInt32},
+		{"max_code_block_size", c.MaxCodeBlockSize, 0, 64 << 20},
+	} {
+		if f.value < f.min || f.value > f.max {
+			fail(f.key, f.value, fmt.Sprintf("must be between %d and %d", f.min, f.max))
+		}
+	}
+
+	if c.WorkspaceRoot != "" {
+		if !filepath.IsAbs(c.WorkspaceRoot) {
+			fail("workspace_root", c.WorkspaceRoot, "must be an absolute path")
+		} else if st, err := os.Stat(c.WorkspaceRoot); err != nil || !st.IsDir() {
+			fail("workspace_root", c.WorkspaceRoot, "must be an existing directory")
+		}
+	}
+	for _, ext := range c.WorkspaceExtensions {
+		if len(ext) < 2 || ext[0] != '.' || ext != strings.ToLower(ext) || strings.ContainsAny(ext, `/\ `) {
+			fail("workspace_extensions", ext, "must be a lowercase extension with a leading dot")
+		}
+	}
+
+	for name, dir := range c.ResourceRoots {
+		switch {
+		case !nameRe.MatchString(name):
+			fail("resource_roots", name, "root names must be lowercase letters, digits, '-' and '_'")
+		case !filepath.IsAbs(dir):
+			fail("resource_roots."+name, dir, "must be an absolute path")
+		default:
+			if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+				fail("resource_roots."+name, dir, "must be an existing directory")
+			}
+		}
+	}
+
+	c.DefaultProfile().validate("", fail)
+	for name, pc := range c.Profiles {
+		pc.validate("profiles."+name+".", fail)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the render settings, reporting keys under prefix.
+func (pc ProfileConfig) validate(prefix string, fail func(key string, value any, reason string)) {
+	if _, ok := styles.Registry[pc.CodeTheme]; !ok && pc.CodeTheme != "" {
+		fail(prefix+"code_theme", pc.CodeTheme, "unknown theme")
+	}
+	if pc.IDPrefix != "" && !idPrefixRe.MatchString(pc.IDPrefix) {
+		fail(prefix+"id_prefix", pc.IDPrefix, "must start with a letter and contain only letters, digits, '-' and '_'")
+	}
+	switch pc.RawHTML {
+	case "", "allow", "escape", "drop":
+	default:
+		fail(prefix+"raw_html", pc.RawHTML, "must be allow, escape or drop")
+	}
+	for _, h := range pc.TrustedHosts {
+		if h == "" || strings.ContainsAny(h, "/:@ ") {
+			fail(prefix+"trusted_hosts", h, "must be a bare host name")
+		}
+	}
+}
+
+// Map returns the config as plugin config keys and values.
+func (c *MarkdownConfig) Map() map[string]any {
+	profiles := make(map[string]any, len(c.Profiles))
+	for name, pc := range c.Profiles {
+		profiles[name] = pc.Map()
+	}
+	return map[string]any{
+		"enabled":                  c.Enabled,
+		"sanitize_html":            c.SanitizeHTML,
+		"allow_inline_styles":      c.AllowInlineStyles,
+		"harden_external_links":    c.HardenExternalLinks,
+		"external_links_new_tab":   c.ExternalLinksNewTab,
+		"trusted_hosts":            append([]string{}, c.TrustedHosts...),
+		"id_prefix":                c.IDPrefix,
+		"raw_html":                 c.RawHTML,
+		"enable_mermaid":           c.EnableMermaid,
+		"enable_math":              c.EnableMath,
+		"enable_table_of_contents": c.EnableTableOfContents,
+		"max_input_size":           c.MaxInputSize,
+		"render_timeout_ms":        c.RenderTimeoutMs,
+		"cache_size":               c.CacheSize,
+		"cache_ttl_seconds":        c.CacheTTLSeconds,
+		"cache_max_bytes":          c.CacheMaxBytes,
+		"batch_workers":            c.BatchWorkers,
+		"max_batch_size":           c.MaxBatchSize,
+		"max_streams":              c.MaxStreams,
+		"max_stream_size":          c.MaxStreamSize,
+		"stream_timeout_ms":        c.StreamTimeoutMs,
+		"max_nesting_depth":        c.MaxNestingDepth,
+		"max_nodes":                c.MaxNodes,
+		"max_links":                c.MaxLinks,
+		"max_table_cells":          c.MaxTableCells,
+		"max_code_block_size":      c.MaxCodeBlockSize,
+		"code_theme":               c.CodeTheme,
+		"workspace_root":           c.WorkspaceRoot,
+		"workspace_extensions":     append([]string{}, c.WorkspaceExtensions...),
+		"resource_roots":           maps.Clone(c.ResourceRoots),
+		"profiles":                 profiles,
+	}
+}
+
+// Map returns the profile as config keys and values.
+func (pc ProfileConfig) Map() map[string]any {
+	return map[string]any{
+		"sanitize_html":            pc.SanitizeHTML,
+		"allow_inline_styles":      pc.AllowInlineStyles,
+		"harden_external_links":    pc.HardenExternalLinks,
+		"external_links_new_tab":   pc.ExternalLinksNewTab,
+		"enable_mermaid":           pc.EnableMermaid,
+		"enable_math":              pc.EnableMath,
+		"enable_table_of_contents": pc.EnableTableOfContents,
+		"code_theme":               pc.CodeTheme,
+		"id_prefix":                pc.IDPrefix,
+		"raw_html":                 pc.RawHTML,
+		"trusted_hosts":            append([]string{}, pc.TrustedHosts...),
+	}
+}
+
+// loader reads and coerces config values, collecting type errors. Error
+// keys are reported under prefix.
+type loader struct {
+	get    func(key string) (any, bool)
+	prefix string
+	errs   []error
+}
+
+func (l *loader) fail(key string, v any, reason string) {
+	l.errs = append(l.errs, &FieldError{Key: l.prefix + key, Value: v, Reason: reason})
+}
+
+// profile loads the render settings of a profile.
+func (l *loader) profile(pc *ProfileConfig) {
+	l.bool("sanitize_html", &pc.SanitizeHTML)
+	l.bool("allow_inline_styles", &pc.AllowInlineStyles)
+	l.bool("harden_external_links", &pc.HardenExternalLinks)
+	l.bool("external_links_new_tab", &pc.ExternalLinksNewTab)
+	l.bool("enable_mermaid", &pc.EnableMermaid)
+	l.bool("enable_math", &pc.EnableMath)
+	l.bool("enable_table_of_contents", &pc.EnableTableOfContents)
+	l.string("code_theme", &pc.CodeTheme)
+	l.string("id_prefix", &pc.IDPrefix)
+	l.string("raw_html", &pc.RawHTML)
+	l.strings("trusted_hosts", &pc.TrustedHosts)
+}
+
+// bool accepts booleans and the strings strconv.ParseBool understands.
+func (l *loader) bool(key string, dst *bool) {
+	v, ok := l.get(key)
+	if !ok || v == nil {
+		return
+	}
+	switch b := v.(type) {
+	case bool:
+		*dst = b
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(b))
+		if err != nil {
+			l.fail(key, v, "must be a boolean")
+			return
+		}
+		*dst = parsed
+	default:
+		l.fail(key, v, "must be a boolean")
+	}
+}
+
+// int accepts integers, integral floats (as decoded from JSON), and
+// decimal strings that fit in an int. Ranges are checked by Validate.
+func (l *loader) int(key string, dst *int) {
+	v, ok := l.get(key)
+	if !ok || v == nil {
+		return
+	}
+	switch n := v.(type) {
+	case int:
+		*dst = n
+	case int64:
+		if n < math.MinInt || n > math.MaxInt {
+			l.fail(key, v, "does not fit in an int")
+			return
+		}
+		*dst = int(n)
+	case float64:
+		if n != math.Trunc(n) {
+			l.fail(key, v, "must be an integer")
+			return
+		}
+		if n < math.MinInt || n >= -math.MinInt {
+			l.fail(key, v, "does not fit in an int")
+			return
+		}
+		*dst = int(n)
+	case string:
+		parsed, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil {
+			l.fail(key, v, "must be an integer")
+			return
+		}
+		*dst = parsed
+	default:
+		l.fail(key, v, "must be an integer")
+	}
+}
+
+// string accepts strings only. A blank code_theme or raw_html means
+// the parser default.
+func (l *loader) string(key string, dst *string) {
+	v, ok := l.get(key)
+	if !ok || v == nil {
+		return
+	}
+	s, ok := v.(string)
+	if !ok {
+		l.fail(key, v, "must be a string")
+		return
+	}
+	*dst = strings.TrimSpace(s)
+}
+
+// strings accepts string lists, JSON arrays of strings, and
+// comma-separated strings. Empty entries are dropped.
+func (l *loader) strings(key string, dst *[]string) {
+	v, ok := l.get(key)
+	if !ok || v == nil {
+		return
+	}
+	var items []string
+	switch list := v.(type) {
+	case []string:
+		items = list
+	case []any:
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				l.fail(key, v, "must be a list of strings")
+				return
+			}
+			items = append(items, s)
+		}
+	case string:
+		items = strings.Split(list, ",")
+	default:
+		l.fail(key, v, "must be a list of strings")
+		return
+	}
+
+	out := make([]string, 0, len(items))
+	for _, s := range items {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	*dst = out
+}
+
+// stringMap accepts objects of strings and comma-separated name=value
+// strings. Names and values are trimmed; empty entries are dropped.
+func (l *loader) stringMap(key string, dst *map[string]string) {
+	v, ok := l.get(key)
+	if !ok || v == nil {
+		return
+	}
+	out := make(map[string]string)
+	add := func(name, value string) {
+		if name, value = strings.TrimSpace(name), strings.TrimSpace(value); name != "" || value != "" {
+			out[name] = value
+		}
+	}
+	switch m := v.(type) {
+	case map[string]string:
+		for name, value := range m {
+			add(name, value)
+		}
+	case map[string]any:
+		for name, item := range m {
+			s, ok := item.(string)
+			if !ok {
+				l.fail(key, v, "must be an object of strings")
+				return
+			}
+			add(name, s)
+		}
+	case string:
+		for _, pair := range strings.Split(m, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				l.fail(key, v, "entries must be name=value")
+				return
+			}
+			add(name, value)
+		}
+	default:
+		l.fail(key, v, "must be an object of strings")
+		return
+	}
+	*dst = out
+}
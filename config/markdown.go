@@ -4,22 +4,163 @@ package config
 type MarkdownConfig struct {
 	Enabled               bool   `json:"enabled"`
 	SanitizeHTML          bool   `json:"sanitize_html"`
+	AllowInlineStyles     bool   `json:"allow_inline_styles"`
+	HardenExternalLinks   bool   `json:"harden_external_links"`
+	ExternalLinksNewTab   bool   `json:"external_links_new_tab"`
 	EnableMermaid         bool   `json:"enable_mermaid"`
 	EnableMath            bool   `json:"enable_math"`
 	EnableTableOfContents bool   `json:"enable_table_of_contents"`
 	MaxInputSize          int    `json:"max_input_size"`
+	RenderTimeoutMs       int    `json:"render_timeout_ms"`
+	CacheSize             int    `json:"cache_size"`
+	CacheTTLSeconds       int    `json:"cache_ttl_seconds"`
+	CacheMaxBytes         int    `json:"cache_max_bytes"`
+	BatchWorkers          int    `json:"batch_workers"`
+	MaxBatchSize          int    `json:"max_batch_size"`
+	MaxStreams            int    `json:"max_streams"`
+	MaxStreamSize         int    `json:"max_stream_size"`
+	StreamTimeoutMs       int    `json:"stream_timeout_ms"`
+	MaxNestingDepth       int    `json:"max_nesting_depth"`
+	MaxNodes              int    `json:"max_nodes"`
+	MaxLinks              int    `json:"max_links"`
+	MaxTableCells         int    `json:"max_table_cells"`
+	MaxCodeBlockSize      int    `json:"max_code_block_size"`
 	CodeTheme             string `json:"code_theme"`
+	IDPrefix              string `json:"id_prefix"`
+	RawHTML               string `json:"raw_html"`
+
+	TrustedHosts []string `json:"trusted_hosts"`
+
+	// WorkspaceRoot is the directory the file tools read from; empty
+	// disables them. WorkspaceExtensions lists the extensions they may
+	// read.
+	WorkspaceRoot       string   `json:"workspace_root"`
+	WorkspaceExtensions []string `json:"workspace_extensions"`
+
+	// ResourceRoots maps root names to directories published as MCP
+	// resources under markdown://<name>/. Files must have one of the
+	// WorkspaceExtensions.
+	ResourceRoots map[string]string `json:"resource_roots"`
+
+	// Profiles are named render setting bundles selectable per request.
+	Profiles map[string]ProfileConfig `json:"profiles"`
+}
+
+// ProfileConfig is the full set of render settings for one profile.
+// Settings a profile does not override come from the top-level config.
+type ProfileConfig struct {
+	SanitizeHTML          bool     `json:"sanitize_html"`
+	AllowInlineStyles     bool     `json:"allow_inline_styles"`
+	HardenExternalLinks   bool     `json:"harden_external_links"`
+	ExternalLinksNewTab   bool     `json:"external_links_new_tab"`
+	EnableMermaid         bool     `json:"enable_mermaid"`
+	EnableMath            bool     `json:"enable_math"`
+	EnableTableOfContents bool     `json:"enable_table_of_contents"`
+	CodeTheme             string   `json:"code_theme"`
+	IDPrefix              string   `json:"id_prefix"`
+	RawHTML               string   `json:"raw_html"`
+	TrustedHosts          []string `json:"trusted_hosts"`
+}
+
+// DefaultProfile returns the top-level render settings as a profile.
+func (c *MarkdownConfig) DefaultProfile() ProfileConfig {
+	return ProfileConfig{
+		SanitizeHTML:          c.SanitizeHTML,
+		AllowInlineStyles:     c.AllowInlineStyles,
+		HardenExternalLinks:   c.HardenExternalLinks,
+		ExternalLinksNewTab:   c.ExternalLinksNewTab,
+		EnableMermaid:         c.EnableMermaid,
+		EnableMath:            c.EnableMath,
+		EnableTableOfContents: c.EnableTableOfContents,
+		CodeTheme:             c.CodeTheme,
+		IDPrefix:              c.IDPrefix,
+		RawHTML:               c.RawHTML,
+		TrustedHosts:          c.TrustedHosts,
+	}
+}
+
+// builtinProfiles are the overrides behind the profiles every config
+// starts with. Configured profiles of the same name override them
+// further.
+var builtinProfiles = map[string]map[string]any{
+	// chat renders untrusted conversational text: raw HTML shown as
+	// text, links hardened, ids namespaced.
+	"chat": {
+		"sanitize_html":            true,
+		"allow_inline_styles":      false,
+		"raw_html":                 "escape",
+		"harden_external_links":    true,
+		"external_links_new_tab":   true,
+		"enable_table_of_contents": false,
+		"id_prefix":                "user-content-",
+	},
+	// docs renders first-party documentation with TOC, math and
+	// diagrams. Heading anchors use the top-level id_prefix.
+	"docs": {
+		"sanitize_html":            true,
+		"raw_html":                 "allow",
+		"enable_table_of_contents": true,
+		"enable_math":              true,
+		"enable_mermaid":           true,
+	},
+	// email keeps inline styles, which mail clients need instead of
+	// stylesheets.
+	"email": {
+		"sanitize_html":            true,
+		"allow_inline_styles":      true,
+		"raw_html":                 "allow",
+		"external_links_new_tab":   true,
+		"enable_table_of_contents": false,
+		"enable_mermaid":           false,
+		"enable_math":              false,
+	},
+	// untrusted is the strictest: raw HTML and styles dropped, no
+	// extensions.
+	"untrusted": {
+		"sanitize_html":            true,
+		"allow_inline_styles":      false,
+		"raw_html":                 "drop",
+		"harden_external_links":    true,
+		"external_links_new_tab":   true,
+		"enable_table_of_contents": false,
+		"enable_mermaid":           false,
+		"enable_math":              false,
+		"id_prefix":                "user-content-",
+	},
 }
 
 // DefaultConfig returns the default markdown configuration.
 func DefaultConfig() *MarkdownConfig {
-	return &MarkdownConfig{
+	cfg := &MarkdownConfig{
 		Enabled:               true,
 		SanitizeHTML:          true,
+		AllowInlineStyles:     false,
+		HardenExternalLinks:   true,
+		ExternalLinksNewTab:   false,
 		EnableMermaid:         true,
 		EnableMath:            true,
 		EnableTableOfContents: true,
 		MaxInputSize:          1048576, // 1MB
+		RenderTimeoutMs:       10000,   // 10s
+		CacheSize:             256,
+		CacheTTLSeconds:       300,      // 5m
+		CacheMaxBytes:         64 << 20, // 64MB
+		BatchWorkers:          4,
+		MaxBatchSize:          100,
+		MaxStreams:            256,
+		MaxStreamSize:         64 << 20, // 64MB
+		StreamTimeoutMs:       120000,   // 2m
+		MaxNestingDepth:       100,
+		MaxNodes:              500000,
+		MaxLinks:              10000,
+		MaxTableCells:         50000,
+		MaxCodeBlockSize:      262144, // 256KB
 		CodeTheme:             "monokai",
+		IDPrefix:              "",
+		RawHTML:               "allow",
+		WorkspaceExtensions:   []string{".md", ".markdown"},
+		ResourceRoots:         map[string]string{},
 	}
+	cfg.Profiles, _ = resolveProfiles(cfg, nil)
+	return cfg
 }
@@ -3,6 +3,7 @@ module github.com/orchestra-mcp/markdown
 go 1.25
 
 require (
+	github.com/alecthomas/chroma/v2 v2.23.1
 	github.com/gofiber/fiber/v3 v3.0.0-beta.4
 	github.com/orchestra-mcp/framework v0.0.0
 	github.com/stretchr/testify v1.11.1
@@ -12,7 +13,6 @@ require (
 )
 
 require (
-	github.com/alecthomas/chroma/v2 v2.23.1 // indirect
 	github.com/andybalholm/brotli v1.1.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dlclark/regexp2 v1.11.5 // indirect
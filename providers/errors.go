@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/workspace"
+)
+
+// statusClientClosedRequest is reported when the caller cancelled the
+// request before it finished.
+const statusClientClosedRequest = 499
+
+// Plugin-level error codes, alongside the service ones.
+const (
+	// codeUnavailable reports that the plugin is disabled or not activated.
+	codeUnavailable = "unavailable"
+	// codeInvalidConfig reports a rejected config reload.
+	codeInvalidConfig = "invalid_config"
+	// codeInvalidPath reports a workspace path that is absolute, leaves
+	// the root, or names something other than an allowed file.
+	codeInvalidPath = "invalid_path"
+	// codeNotFound reports a workspace file or stream session that does
+	// not exist.
+	codeNotFound = "not_found"
+	// codeNotAcceptable reports an Accept header no response type matches.
+	codeNotAcceptable = "not_acceptable"
+	// codeUnsupportedMediaType reports a request body type that is
+	// neither JSON nor raw markdown.
+	codeUnsupportedMediaType = "unsupported_media_type"
+	// codeTooManyStreams reports that max_streams sessions are open.
+	codeTooManyStreams = "too_many_streams"
+)
+
+// errNotActive is returned by routes and tools while the plugin is
+// disabled by config or not yet activated.
+var errNotActive = &service.Error{Code: codeUnavailable, Message: "markdown plugin is not active"}
+
+// errorStatuses maps service error codes to HTTP statuses. Unknown codes
+// are internal errors.
+var errorStatuses = map[string]int{
+	service.CodeInvalidInput:      fiber.StatusBadRequest,
+	service.CodeInvalidOptions:    fiber.StatusBadRequest,
+	service.CodeUnsupportedFormat: fiber.StatusBadRequest,
+	service.CodeInputTooLarge:     fiber.StatusRequestEntityTooLarge,
+	service.CodeBatchTooLarge:     fiber.StatusRequestEntityTooLarge,
+	service.CodeLimitExceeded:     fiber.StatusUnprocessableEntity,
+	service.CodeTimeout:           fiber.StatusGatewayTimeout,
+	service.CodeCanceled:          statusClientClosedRequest,
+	service.CodeStreamClosed:      fiber.StatusConflict,
+	codeUnavailable:               fiber.StatusServiceUnavailable,
+	codeInvalidConfig:             fiber.StatusBadRequest,
+	codeInvalidPath:               fiber.StatusBadRequest,
+	codeNotFound:                  fiber.StatusNotFound,
+	codeNotAcceptable:             fiber.StatusNotAcceptable,
+	codeUnsupportedMediaType:      fiber.StatusUnsupportedMediaType,
+	codeTooManyStreams:            fiber.StatusTooManyRequests,
+}
+
+// errorStatus returns the HTTP status for a service error code.
+func errorStatus(code string) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return fiber.StatusInternalServerError
+}
+
+// sendError writes err as {"error": code, "message", "details"} with the
+// status matching its code.
+func sendError(c fiber.Ctx, err error) error {
+	e := service.AsError(err)
+	body := fiber.Map{"error": e.Code, "message": e.Message}
+	if len(e.Details) > 0 {
+		body["details"] = e.Details
+	}
+	return c.Status(errorStatus(e.Code)).JSON(body)
+}
+
+// streamErrorMarker formats the HTML comment that ends a streamed render
+// which failed after the status was sent. It holds the JSON error body
+// sendError would have returned.
+func streamErrorMarker(err error) string {
+	e := service.AsError(err)
+	data, _ := json.Marshal(fiber.Map{"error": e.Code, "message": e.Message})
+	return "<!-- render-error " + strings.ReplaceAll(string(data), "--", `-\u002d`) + " -->\n"
+}
+
+// toolError converts err into the *service.Error returned from MCP tool
+// handlers, so tool failures carry the same code and details as REST.
+func toolError(err error) error {
+	return service.AsError(err)
+}
+
+// invalidBody wraps a request body that does not decode.
+func invalidBody(err error) error {
+	return &service.Error{Code: service.CodeInvalidInput, Message: "invalid request body: " + err.Error(), Err: err}
+}
+
+// streamNotFound reports an unknown or already swept stream session.
+func streamNotFound(id string) error {
+	return &service.Error{Code: codeNotFound, Message: "unknown stream " + id, Details: map[string]any{"id": id}}
+}
+
+// unsupportedMediaType reports a request body type no route accepts.
+func unsupportedMediaType(contentType string) error {
+	return &service.Error{
+		Code:    codeUnsupportedMediaType,
+		Message: fmt.Sprintf("unsupported content type %q; send JSON or a raw markdown body", contentType),
+		Details: map[string]any{"content_type": contentType},
+	}
+}
+
+// streamTooLarge reports a streamed body whose declared length is over
+// max_stream_size.
+func streamTooLarge(max, size int) error {
+	return &service.Error{
+		Code:    service.CodeInputTooLarge,
+		Message: fmt.Sprintf("input exceeds maximum size of %d bytes", max),
+		Details: map[string]any{"limit": max, "actual": size},
+	}
+}
+
+// tooManyStreams reports that no more stream sessions may be opened.
+func tooManyStreams(max int) error {
+	return &service.Error{
+		Code:    codeTooManyStreams,
+		Message: fmt.Sprintf("too many open stream sessions (max %d)", max),
+		Details: map[string]any{"limit": max},
+	}
+}
+
+// invalidConfig wraps a config load failure.
+func invalidConfig(err error) error {
+	return &service.Error{Code: codeInvalidConfig, Message: err.Error(), Err: err}
+}
+
+// workspaceError maps a workspace read failure to its error code.
+func workspaceError(path string, err error) error {
+	details := map[string]any{"path": path}
+	var size *workspace.SizeError
+	switch {
+	case errors.As(err, &size):
+		return &service.Error{
+			Code:    service.CodeInputTooLarge,
+			Message: err.Error(),
+			Details: map[string]any{"path": path, "limit": size.Limit, "size": size.Size},
+			Err:     err,
+		}
+	case errors.Is(err, workspace.ErrDisabled):
+		return &service.Error{Code: codeUnavailable, Message: err.Error(), Err: err}
+	case errors.Is(err, workspace.ErrInvalidPath):
+		return &service.Error{Code: codeInvalidPath, Message: err.Error(), Details: details, Err: err}
+	case errors.Is(err, workspace.ErrNotFound):
+		return &service.Error{Code: codeNotFound, Message: err.Error(), Details: details, Err: err}
+	}
+	return service.AsError(err)
+}
+
+// notAcceptable reports an Accept header matching none of supported.
+func notAcceptable(accept string, supported []string) error {
+	return &service.Error{
+		Code:    codeNotAcceptable,
+		Message: "cannot produce " + accept,
+		Details: map[string]any{"accept": accept, "supported": supported},
+	}
+}
+
+// missingField reports a required tool argument that was not provided.
+func missingField(name string) error {
+	return &service.Error{
+		Code:    service.CodeInvalidInput,
+		Message: name + " is required",
+		Details: map[string]any{"field": name},
+	}
+}
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"github.com/orchestra-mcp/markdown/config"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/workspace"
+)
+
+// readFile reads path from the configured workspace. Files are capped at
+// max_input_size, since larger ones would fail to render anyway.
+func (p *MarkdownPlugin) readFile(input map[string]any) (*workspace.File, error) {
+	path, _ := input["path"].(string)
+	if path == "" {
+		return nil, missingField("path")
+	}
+	cfg := p.Config()
+	if cfg == nil {
+		return nil, errNotActive
+	}
+
+	f, err := newWorkspace(cfg, cfg.WorkspaceRoot).Read(path)
+	if err != nil {
+		return nil, workspaceError(path, err)
+	}
+	return f, nil
+}
+
+// newWorkspace returns the workspace at root with the configured
+// extensions and size cap.
+func newWorkspace(cfg *config.MarkdownConfig, root string) *workspace.Workspace {
+	return &workspace.Workspace{
+		Root:       root,
+		MaxSize:    int64(cfg.MaxInputSize),
+		Extensions: cfg.WorkspaceExtensions,
+	}
+}
+
+func (p *MarkdownPlugin) toolRenderMarkdownFile(svc *service.MarkdownService, input map[string]any) (any, error) {
+	f, err := p.readFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := renderRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	req.Content = string(f.Content)
+
+	result, err := svc.RenderContext(p.toolContext(), req)
+	if err != nil {
+		return nil, toolError(err)
+	}
+	result.File = &f.Info
+
+	return result, nil
+}
+
+func (p *MarkdownPlugin) toolExtractTOCFile(svc *service.MarkdownService, input map[string]any) (any, error) {
+	f, err := p.readFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	toc, err := svc.ExtractTOCContext(p.toolContext(), string(f.Content))
+	if err != nil {
+		return nil, toolError(err)
+	}
+
+	return map[string]any{"toc": toc, "file": f.Info}, nil
+}
+
+func (p *MarkdownPlugin) toolExtractCodeBlocksFile(svc *service.MarkdownService, input map[string]any) (any, error) {
+	f, err := p.readFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := svc.ExtractCodeBlocksContext(p.toolContext(), string(f.Content))
+	if err != nil {
+		return nil, toolError(err)
+	}
+
+	return map[string]any{"code_blocks": blocks, "file": f.Info}, nil
+}
@@ -1,6 +1,11 @@
 package providers
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/orchestra-mcp/framework/app/plugins"
 	"github.com/orchestra-mcp/markdown/config"
 	"github.com/orchestra-mcp/markdown/src/parser"
@@ -10,70 +15,157 @@ import (
 
 // MarkdownPlugin implements the Orchestra plugin interface for markdown parsing.
 type MarkdownPlugin struct {
-	active bool
+	active atomic.Bool
 	ctx    *plugins.PluginContext
-	cfg    *config.MarkdownConfig
-	svc    *service.MarkdownService
+
+	// cfg and svc are swapped together on reload; handlers load svc
+	// once per request so in-flight work finishes on the old instance.
+	cfg      atomic.Pointer[config.MarkdownConfig]
+	svc      atomic.Pointer[service.MarkdownService]
+	reloadMu sync.Mutex
+
+	// life is canceled on Deactivate, stopping in-flight tool calls and
+	// the stream sweeper.
+	life atomic.Pointer[lifetime]
+
+	streams *streamRegistry
+	// extensions outlives reloads so contributed syntax stays registered.
+	extensions *parser.Registry
+}
+
+// lifetime is the context of one activation.
+type lifetime struct {
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewMarkdownPlugin creates a new Markdown plugin instance.
-func NewMarkdownPlugin() *MarkdownPlugin { return &MarkdownPlugin{} }
+func NewMarkdownPlugin() *MarkdownPlugin {
+	return &MarkdownPlugin{
+		streams:    newStreamRegistry(),
+		extensions: parser.NewRegistry(),
+	}
+}
 
 func (p *MarkdownPlugin) ID() string             { return "orchestra/markdown" }
 func (p *MarkdownPlugin) Name() string           { return "Markdown Parser" }
 func (p *MarkdownPlugin) Version() string        { return "0.1.0" }
 func (p *MarkdownPlugin) Dependencies() []string { return nil }
-func (p *MarkdownPlugin) IsActive() bool         { return p.active }
+func (p *MarkdownPlugin) IsActive() bool         { return p.active.Load() }
 func (p *MarkdownPlugin) FeatureFlag() string    { return "markdown" }
 func (p *MarkdownPlugin) ConfigKey() string      { return "markdown" }
 
 func (p *MarkdownPlugin) DefaultConfig() map[string]any {
-	return map[string]any{
-		"sanitize_html":            true,
-		"enable_mermaid":           true,
-		"enable_math":              true,
-		"enable_table_of_contents": true,
-		"max_input_size":           1048576,
-		"code_theme":               "monokai",
-	}
+	return config.DefaultConfig().Map()
 }
 
-// Activate initializes the parser and service with config values.
+// Activate loads and validates the plugin config, then initializes the
+// parser and service. A disabled plugin activates without a service and
+// its routes and tools report it unavailable.
 func (p *MarkdownPlugin) Activate(ctx *plugins.PluginContext) error {
-	p.ctx = ctx
-	p.cfg = config.DefaultConfig()
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
 
-	if v, ok := ctx.GetConfig("code_theme"); ok {
-		if s, ok := v.(string); ok && s != "" {
-			p.cfg.CodeTheme = s
-		}
+	p.ctx = ctx
+	if err := p.reloadLocked(); err != nil {
+		return err
 	}
-
-	opts := types.RenderOptions{
-		SanitizeHTML:  p.cfg.SanitizeHTML,
-		EnableMermaid: p.cfg.EnableMermaid,
-		EnableMath:    p.cfg.EnableMath,
-		EnableTOC:     p.cfg.EnableTableOfContents,
-		CodeTheme:     p.cfg.CodeTheme,
+	life, cancel := context.WithCancel(context.Background())
+	if old := p.life.Swap(&lifetime{ctx: life, cancel: cancel}); old != nil {
+		old.cancel()
 	}
-
-	mdParser := parser.New(opts)
-	p.svc = service.New(mdParser, p.cfg.SanitizeHTML, p.cfg.MaxInputSize)
-
-	p.active = true
+	go p.streams.sweepEvery(life, streamSweepInterval)
+	p.active.Store(true)
 	ctx.Logger.Info().Str("plugin", p.ID()).Msg("markdown plugin activated")
 	return nil
 }
 
-// Deactivate shuts down the markdown plugin.
+// Deactivate shuts down the markdown plugin, canceling tool calls in
+// flight and closing open stream sessions.
 func (p *MarkdownPlugin) Deactivate() error {
-	p.active = false
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	p.active.Store(false)
+	if life := p.life.Swap(nil); life != nil {
+		life.cancel()
+	}
+	p.streams.closeAll()
+	p.svc.Store(nil)
 	return nil
 }
 
-// Service returns the underlying MarkdownService.
+// Config returns the effective config, or nil before activation.
+func (p *MarkdownPlugin) Config() *config.MarkdownConfig {
+	return p.cfg.Load()
+}
+
+// Service returns the current MarkdownService, or nil while the plugin
+// is disabled or inactive. Callers should hold on to the returned
+// instance for the duration of one operation.
 func (p *MarkdownPlugin) Service() *service.MarkdownService {
-	return p.svc
+	return p.svc.Load()
+}
+
+// Extensions returns the registry other plugins contribute markdown
+// syntax to. Registrations apply to every profile from the next render.
+func (p *MarkdownPlugin) Extensions() *parser.Registry {
+	return p.extensions
+}
+
+// buildService creates a MarkdownService from cfg, with one profile per
+// configured profile, applying the extensions in reg.
+func buildService(cfg *config.MarkdownConfig, reg *parser.Registry) *service.MarkdownService {
+	profiles := make(map[string]service.Profile, len(cfg.Profiles))
+	for name, pc := range cfg.Profiles {
+		profiles[name] = service.Profile{
+			Options:  renderOptions(cfg, pc),
+			Sanitize: pc.SanitizeHTML,
+		}
+	}
+
+	return service.NewWithOptions(parser.NewWithRegistry(renderOptions(cfg, cfg.DefaultProfile()), reg), service.Options{
+		Sanitize:      cfg.SanitizeHTML,
+		MaxInputSize:  cfg.MaxInputSize,
+		RenderTimeout: time.Duration(cfg.RenderTimeoutMs) * time.Millisecond,
+		CacheSize:     cfg.CacheSize,
+		CacheTTL:      time.Duration(cfg.CacheTTLSeconds) * time.Second,
+		CacheMaxBytes: int64(cfg.CacheMaxBytes),
+		BatchWorkers:  cfg.BatchWorkers,
+		MaxBatchSize:  cfg.MaxBatchSize,
+		MaxStreamSize: cfg.MaxStreamSize,
+		StreamTimeout: time.Duration(cfg.StreamTimeoutMs) * time.Millisecond,
+		Profiles:      profiles,
+	})
+}
+
+// renderOptions builds parser options from a profile's settings and the
+// config-wide structural limits.
+func renderOptions(cfg *config.MarkdownConfig, pc config.ProfileConfig) types.RenderOptions {
+	return types.RenderOptions{
+		SanitizeHTML:  pc.SanitizeHTML,
+		EnableMermaid: pc.EnableMermaid,
+		EnableMath:    pc.EnableMath,
+		EnableTOC:     pc.EnableTableOfContents,
+		CodeTheme:     pc.CodeTheme,
+		RawHTML:       pc.RawHTML,
+		Sanitize: types.SanitizePolicy{
+			AllowStyles: pc.AllowInlineStyles,
+			Links: types.LinkPolicy{
+				HardenExternal: pc.HardenExternalLinks,
+				TargetBlank:    pc.ExternalLinksNewTab,
+				TrustedHosts:   pc.TrustedHosts,
+			},
+			IDPrefix: pc.IDPrefix,
+		},
+		Limits: types.Limits{
+			MaxDepth:         cfg.MaxNestingDepth,
+			MaxNodes:         cfg.MaxNodes,
+			MaxLinks:         cfg.MaxLinks,
+			MaxTableCells:    cfg.MaxTableCells,
+			MaxCodeBlockSize: cfg.MaxCodeBlockSize,
+		},
+	}
 }
 
 // Services returns ServiceDefinitions for the DI registry.
@@ -81,7 +173,11 @@ func (p *MarkdownPlugin) Services() []plugins.ServiceDefinition {
 	return []plugins.ServiceDefinition{
 		{
 			ID:      "markdown",
-			Factory: func() any { return p.svc },
+			Factory: func() any { return p.Service() },
+		},
+		{
+			ID:      "markdown.extensions",
+			Factory: func() any { return p.extensions },
 		},
 	}
 }
@@ -94,4 +190,8 @@ var (
 	_ plugins.HasServices    = (*MarkdownPlugin)(nil)
 	_ plugins.HasRoutes      = (*MarkdownPlugin)(nil)
 	_ plugins.HasMcpTools    = (*MarkdownPlugin)(nil)
+
+	// Resources are host-integration only; switch to the framework's
+	// interface once it has a resource hook.
+	_ HasMcpResources = (*MarkdownPlugin)(nil)
 )
@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/orchestra-mcp/markdown/config"
+)
+
+// Reload re-reads the plugin config and atomically swaps in a service
+// built from it. Requests already running keep the service they started
+// with. An invalid config is rejected and the current service stays in
+// place. A plugin that is not active cannot be reloaded.
+func (p *MarkdownPlugin) Reload() error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	if !p.IsActive() {
+		return errNotActive
+	}
+	return p.reloadLocked()
+}
+
+// reloadLocked loads the config and swaps in its service. The caller
+// holds reloadMu.
+func (p *MarkdownPlugin) reloadLocked() error {
+	cfg, err := config.Load(p.ctx.GetConfig)
+	if err != nil {
+		return fmt.Errorf("invalid markdown config: %w", err)
+	}
+
+	if !cfg.Enabled {
+		p.svc.Store(nil)
+		p.cfg.Store(cfg)
+		p.ctx.Logger.Info().Str("plugin", p.ID()).Msg("markdown plugin disabled by config")
+		return nil
+	}
+
+	p.svc.Store(buildService(cfg, p.extensions))
+	p.cfg.Store(cfg)
+	p.ctx.Logger.Info().Str("plugin", p.ID()).Msg("markdown config loaded")
+	return nil
+}
+
+// OnConfigChange is the config-change notification hook: it reloads the
+// service, logging and keeping the current one if the config is invalid.
+// Changes arriving while the plugin is inactive are ignored.
+func (p *MarkdownPlugin) OnConfigChange() {
+	if err := p.Reload(); err != nil && !errors.Is(err, errNotActive) {
+		p.ctx.Logger.Warn().Err(err).Str("plugin", p.ID()).Msg("markdown config reload rejected")
+	}
+}
+
+// handleReload reloads the config and returns the effective config.
+func (p *MarkdownPlugin) handleReload(c fiber.Ctx) error {
+	if err := p.Reload(); err != nil {
+		return sendError(c, reloadError(err))
+	}
+	return c.JSON(p.Config())
+}
+
+// toolReloadConfig reloads the config and returns the effective config.
+func (p *MarkdownPlugin) toolReloadConfig(map[string]any) (any, error) {
+	if err := p.Reload(); err != nil {
+		return nil, reloadError(err)
+	}
+	return p.Config(), nil
+}
+
+// reloadError maps a Reload failure to its error code: unavailable for
+// an inactive plugin, invalid_config otherwise.
+func reloadError(err error) error {
+	if errors.Is(err, errNotActive) {
+		return err
+	}
+	return invalidConfig(err)
+}
@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/orchestra-mcp/markdown/src/resource"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// maxResources caps the number of resources listed across all roots.
+const maxResources = 1000
+
+// McpResource describes one entry of an MCP resources/list result.
+type McpResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// HasMcpResources is the resource hook hosts look for. The plugin
+// framework defines none, so resources are served only by hosts that
+// type-assert plugins against this interface.
+type HasMcpResources interface {
+	McpResourceTemplates() []McpResourceTemplate
+	McpResources() ([]McpResource, error)
+	ReadMcpResource(uri string) (*McpResourceContents, error)
+}
+
+// McpResourceTemplate describes one entry of an MCP
+// resources/templates/list result.
+type McpResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// McpResourceContents is the text content of a resources/read result.
+type McpResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// McpResourceTemplates returns the template matching every markdown
+// resource URI.
+func (p *MarkdownPlugin) McpResourceTemplates() []McpResourceTemplate {
+	return []McpResourceTemplate{{
+		URITemplate: resource.Template,
+		Name:        "markdown",
+		Description: "Markdown file under a configured resource root, rendered as html (default), text, or outline. " +
+			"format also accepts the MIME types text/html, text/plain and application/json.",
+	}}
+}
+
+// McpResources lists the markdown files under every resource root as
+// HTML resources, sorted by root name and path, up to maxResources.
+func (p *MarkdownPlugin) McpResources() ([]McpResource, error) {
+	cfg := p.Config()
+	if cfg == nil || p.Service() == nil || !p.IsActive() {
+		return nil, errNotActive
+	}
+
+	var out []McpResource
+	for _, name := range slices.Sorted(maps.Keys(cfg.ResourceRoots)) {
+		entries, err := newWorkspace(cfg, cfg.ResourceRoots[name]).List(maxResources - len(out))
+		if err != nil {
+			return nil, toolError(err)
+		}
+		for _, e := range entries {
+			out = append(out, McpResource{
+				URI:      resource.URI{Root: name, Path: e.Path}.String(),
+				Name:     name + "/" + e.Path,
+				MimeType: resource.MimeType(resource.FormatHTML),
+				Size:     e.Size,
+			})
+		}
+		if len(out) >= maxResources {
+			break
+		}
+	}
+	return out, nil
+}
+
+// ReadMcpResource reads the file a resource URI names and renders it in
+// the URI's format. Errors are *service.Error values with the same codes
+// as the file tools.
+func (p *MarkdownPlugin) ReadMcpResource(uri string) (*McpResourceContents, error) {
+	cfg, svc := p.Config(), p.Service()
+	if cfg == nil || svc == nil || !p.IsActive() {
+		return nil, errNotActive
+	}
+
+	u, err := resource.Parse(uri)
+	if err != nil {
+		return nil, &service.Error{
+			Code:    service.CodeInvalidInput,
+			Message: err.Error(),
+			Details: map[string]any{"uri": uri},
+			Err:     err,
+		}
+	}
+	root, ok := cfg.ResourceRoots[u.Root]
+	if !ok {
+		return nil, &service.Error{
+			Code:    codeNotFound,
+			Message: "unknown resource root " + u.Root,
+			Details: map[string]any{"uri": uri, "root": u.Root},
+		}
+	}
+	f, err := newWorkspace(cfg, root).Read(u.Path)
+	if err != nil {
+		return nil, workspaceError(u.Path, err)
+	}
+
+	contents := &McpResourceContents{URI: uri, MimeType: resource.MimeType(u.Format)}
+	switch u.Format {
+	case resource.FormatOutline:
+		toc, err := svc.ExtractTOCContext(p.toolContext(), string(f.Content))
+		if err != nil {
+			return nil, toolError(err)
+		}
+		if toc == nil {
+			toc = []types.TOCEntry{}
+		}
+		data, err := json.Marshal(map[string]any{"toc": toc, "file": f.Info})
+		if err != nil {
+			return nil, toolError(err)
+		}
+		contents.Text = string(data)
+	case resource.FormatText:
+		result, err := svc.RenderContext(p.toolContext(), types.RenderRequest{Content: string(f.Content), Format: types.FormatText})
+		if err != nil {
+			return nil, toolError(err)
+		}
+		contents.Text = result.Text
+	default:
+		result, err := svc.RenderContext(p.toolContext(), types.RenderRequest{Content: string(f.Content), Format: types.FormatHTML})
+		if err != nil {
+			return nil, toolError(err)
+		}
+		contents.Text = result.HTML
+	}
+	return contents, nil
+}
@@ -1,7 +1,18 @@
 package providers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+
 	"github.com/gofiber/fiber/v3"
+	"github.com/orchestra-mcp/markdown/src/openapi"
+	"github.com/orchestra-mcp/markdown/src/service"
 	"github.com/orchestra-mcp/markdown/src/types"
 )
 
@@ -9,73 +20,352 @@ import (
 func (p *MarkdownPlugin) RegisterRoutes(group fiber.Router) {
 	g := group.Group("/markdown")
 
-	g.Post("/render", p.handleRender)
-	g.Post("/toc", p.handleTOC)
-	g.Post("/code-blocks", p.handleCodeBlocks)
+	g.Post("/render", p.enabled(p.handleRender))
+	g.Post("/render/batch", p.enabled(p.handleRenderBatch))
+	g.Post("/render/stream", p.enabled(p.handleRenderStream))
+	g.Post("/toc", p.enabled(p.handleTOC))
+	g.Post("/code-blocks", p.enabled(p.handleCodeBlocks))
+	g.Get("/preview", p.enabled(p.handlePreview))
+	g.Post("/preview", p.enabled(p.handlePreview))
+	g.Get("/cache", p.enabled(p.handleCacheStats))
+	g.Get("/config", p.handleConfig)
+	g.Post("/config/reload", p.handleReload)
+
+	g.Get("/openapi.json", p.handleOpenAPI)
+
+	g.Post("/stream", p.enabled(p.handleStreamCreate))
+	g.Post("/stream/:id", p.enabled(p.handleStreamAppend))
+	g.Get("/stream/:id", p.enabled(p.handleStreamEvents))
+}
+
+// renderResponseTypes are the media types POST /markdown/render can
+// answer with, the default first.
+var renderResponseTypes = []string{fiber.MIMEApplicationJSON, fiber.MIMETextHTML, fiber.MIMETextPlain}
+
+// responseFormats maps the fragment response types to the render
+// formats they can return, the default first.
+var responseFormats = map[string][]string{
+	fiber.MIMETextHTML:  {types.FormatHTML, types.FormatDocument},
+	fiber.MIMETextPlain: {types.FormatText},
+}
+
+// handleRender renders a JSON request, or a raw markdown body with the
+// other fields in query parameters. Accept picks the response: the full
+// RenderResult as JSON, or only the HTML or text.
+func (p *MarkdownPlugin) handleRender(c fiber.Ctx, svc *service.MarkdownService) error {
+	c.Vary(fiber.HeaderAccept)
+	accept := c.Accepts(renderResponseTypes...)
+	if accept == "" {
+		return sendError(c, notAcceptable(c.Get(fiber.HeaderAccept), renderResponseTypes))
+	}
+
+	req, err := bindRenderRequest(c)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	if formats, ok := responseFormats[accept]; ok {
+		switch {
+		case req.Format == "":
+			req.Format = formats[0]
+		case !slices.Contains(formats, req.Format):
+			return sendError(c, notAcceptable(accept, []string{fiber.MIMEApplicationJSON}))
+		}
+	}
+
+	key, err := svc.CacheKey(req)
+	if err != nil {
+		return sendError(c, err)
+	}
+	// Fragments are a different representation than the JSON result of
+	// the same render, so they get their own tag.
+	if accept != fiber.MIMEApplicationJSON {
+		key += "-" + req.Format
+	}
+	etag := `"` + key + `"`
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		c.Set(fiber.HeaderETag, etag)
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	result, err := svc.RenderContext(ctx, req)
+	if err != nil {
+		return sendError(c, err)
+	}
+	// Only a produced representation gets a validator.
+	c.Set(fiber.HeaderETag, etag)
+
+	switch accept {
+	case fiber.MIMETextHTML:
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(result.HTML)
+	case fiber.MIMETextPlain:
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(result.Text)
+	}
+	return c.JSON(result)
 }
 
-func (p *MarkdownPlugin) handleRender(c fiber.Ctx) error {
+// bindRenderRequest reads a JSON render request, or a raw markdown body
+// with the other fields in query parameters.
+func bindRenderRequest(c fiber.Ctx) (types.RenderRequest, error) {
+	markdown, err := isMarkdownBody(c.Get(fiber.HeaderContentType))
+	if err != nil {
+		return types.RenderRequest{}, err
+	}
+	if markdown {
+		return types.RenderRequest{
+			Content: string(c.Body()),
+			Format:  c.Query("format"),
+			Profile: c.Query("profile"),
+			Options: types.RenderOptions{RawHTML: c.Query("raw_html")},
+		}, nil
+	}
+
 	var body struct {
-		Content string               `json:"content"`
-		Options *types.RenderOptions `json:"options,omitempty"`
+		Content        string               `json:"content"`
+		Format         string               `json:"format,omitempty"`
+		Options        *types.RenderOptions `json:"options,omitempty"`
+		Profile        string               `json:"profile,omitempty"`
+		PreviousBlocks []string             `json:"previous_blocks,omitempty"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid_body", "message": err.Error(),
-		})
+		return types.RenderRequest{}, invalidBody(err)
 	}
 
-	req := types.RenderRequest{Content: body.Content}
+	req := types.RenderRequest{
+		Content:        body.Content,
+		Format:         body.Format,
+		Profile:        body.Profile,
+		PreviousBlocks: body.PreviousBlocks,
+	}
 	if body.Options != nil {
 		req.Options = *body.Options
 	}
+	return req, nil
+}
+
+// isMarkdownBody reports whether a Content-Type declares a raw markdown
+// body rather than a JSON request. Any type but JSON is markdown, so
+// curl's default form type works; a missing type means JSON. Multipart
+// bodies and malformed types are rejected.
+func isMarkdownBody(contentType string) (bool, error) {
+	if contentType == "" {
+		return false, nil
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	switch {
+	case err != nil:
+		return false, unsupportedMediaType(contentType)
+	case media == fiber.MIMEApplicationJSON || strings.HasSuffix(media, "+json"):
+		return false, nil
+	case strings.HasPrefix(media, "multipart/"):
+		return false, unsupportedMediaType(media)
+	}
+	return true, nil
+}
+
+// previewCSP keeps preview pages from running scripts or loading
+// anything but images, whatever the rendered markdown contains.
+const previewCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src http: https: data:; base-uri 'none'; form-action 'none'"
+
+// handlePreview renders markdown as a standalone HTML page for viewing in
+// a browser. GET renders the workspace file named by the path query
+// parameter; POST takes the same bodies as /render. The format is always
+// document.
+func (p *MarkdownPlugin) handlePreview(c fiber.Ctx, svc *service.MarkdownService) error {
+	var req types.RenderRequest
+	if c.Method() == fiber.MethodGet {
+		f, err := p.readFile(map[string]any{"path": c.Query("path")})
+		if err != nil {
+			return sendError(c, err)
+		}
+		req = types.RenderRequest{
+			Content: string(f.Content),
+			Profile: c.Query("profile"),
+			Options: types.RenderOptions{RawHTML: c.Query("raw_html")},
+		}
+	} else {
+		var err error
+		if req, err = bindRenderRequest(c); err != nil {
+			return sendError(c, err)
+		}
+	}
+	req.Format = types.FormatDocument
+	req.PreviousBlocks = nil
 
-	result, err := p.svc.Render(req)
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	result, err := svc.RenderContext(ctx, req)
 	if err != nil {
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
-			"error": "render_failed", "message": err.Error(),
-		})
+		return sendError(c, err)
 	}
 
-	return c.JSON(result)
+	c.Set(fiber.HeaderContentSecurityPolicy, previewCSP)
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(result.HTML)
+}
+
+func (p *MarkdownPlugin) handleRenderBatch(c fiber.Ctx, svc *service.MarkdownService) error {
+	var body struct {
+		Items []types.BatchItem `json:"items"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return sendError(c, invalidBody(err))
+	}
+
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	results, err := svc.RenderBatch(ctx, body.Items)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"results": results})
+}
+
+// handleRenderStream renders a raw markdown request body and streams the
+// HTML back block by block. Render options come from query parameters.
+// The first block is rendered before the status is sent, so an input
+// that fails up front gets a normal error response; a later failure
+// ends the body with a render-error marker. A declared length over
+// max_stream_size is refused before reading the body; the service
+// enforces the limit on the bytes actually read.
+func (p *MarkdownPlugin) handleRenderStream(c fiber.Ctx, svc *service.MarkdownService) error {
+	if max, n := p.Config().MaxStreamSize, c.Request().Header.ContentLength(); max > 0 && n > max {
+		return sendError(c, streamTooLarge(max, n))
+	}
+	body := c.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	opts := types.RenderOptions{RawHTML: c.Query("raw_html")}
+	// The writer runs after the handler returns, so it owns cancel.
+	ctx, cancel := p.requestContext(c)
+	br := svc.NewBlockRenderer(ctx, body, opts)
+	first, err := br.Next()
+	if err != nil && !errors.Is(err, io.EOF) {
+		cancel()
+		return sendError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer w.Flush()
+		for html := first; err == nil; html, err = br.Next() {
+			if _, werr := w.WriteString(html + "\n"); werr != nil {
+				return
+			}
+			if w.Flush() != nil {
+				return
+			}
+		}
+		if !errors.Is(err, io.EOF) {
+			p.ctx.Logger.Warn().Err(err).Str("plugin", p.ID()).Msg("streamed render failed")
+			_, _ = w.WriteString(streamErrorMarker(err))
+		}
+	})
 }
 
-func (p *MarkdownPlugin) handleTOC(c fiber.Ctx) error {
+func (p *MarkdownPlugin) handleTOC(c fiber.Ctx, svc *service.MarkdownService) error {
 	var body struct {
 		Content string `json:"content"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid_body", "message": err.Error(),
-		})
+		return sendError(c, invalidBody(err))
 	}
 
-	toc, err := p.svc.ExtractTOC(body.Content)
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	toc, err := svc.ExtractTOCContext(ctx, body.Content)
 	if err != nil {
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
-			"error": "extract_failed", "message": err.Error(),
-		})
+		return sendError(c, err)
 	}
 
 	return c.JSON(fiber.Map{"toc": toc})
 }
 
-func (p *MarkdownPlugin) handleCodeBlocks(c fiber.Ctx) error {
+func (p *MarkdownPlugin) handleCodeBlocks(c fiber.Ctx, svc *service.MarkdownService) error {
 	var body struct {
 		Content string `json:"content"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid_body", "message": err.Error(),
-		})
+		return sendError(c, invalidBody(err))
 	}
 
-	blocks, err := p.svc.ExtractCodeBlocks(body.Content)
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	blocks, err := svc.ExtractCodeBlocksContext(ctx, body.Content)
 	if err != nil {
-		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
-			"error": "extract_failed", "message": err.Error(),
-		})
+		return sendError(c, err)
 	}
 
 	return c.JSON(fiber.Map{"code_blocks": blocks})
 }
+
+func (p *MarkdownPlugin) handleCacheStats(c fiber.Ctx, svc *service.MarkdownService) error {
+	return c.JSON(svc.CacheStats())
+}
+
+// handleConfig returns the effective plugin config after defaults,
+// coercion and validation.
+func (p *MarkdownPlugin) handleConfig(c fiber.Ctx) error {
+	cfg := p.Config()
+	if cfg == nil {
+		return sendError(c, errNotActive)
+	}
+	return c.JSON(cfg)
+}
+
+// handleOpenAPI serves the OpenAPI document of these routes, with the
+// server URL set to the prefix they are mounted under.
+func (p *MarkdownPlugin) handleOpenAPI(c fiber.Ctx) error {
+	base := strings.TrimSuffix(c.Path(), "/markdown/openapi.json")
+	if base == "" {
+		base = "/"
+	}
+	return c.JSON(openapi.Document(p.Version(), base))
+}
+
+// requestContext returns the context a REST render runs under: the
+// request's user context, also canceled when the server shuts down or the
+// plugin deactivates. fasthttp does not report client disconnects, so an
+// abandoned render still runs until its time budget is spent. Callers
+// must call the returned cancel function.
+func (p *MarkdownPlugin) requestContext(c fiber.Ctx) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(c.Context())
+	stopServer := context.AfterFunc(c.RequestCtx(), cancel)
+	stopLife := context.AfterFunc(p.toolContext(), cancel)
+	return ctx, func() {
+		stopServer()
+		stopLife()
+		cancel()
+	}
+}
+
+// enabled guards a handler so it answers 503 until the plugin is active
+// and enabled. The handler gets the service current when the request
+// arrived and keeps using it even if a reload swaps in another.
+func (p *MarkdownPlugin) enabled(h func(fiber.Ctx, *service.MarkdownService) error) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		svc := p.Service()
+		if svc == nil || !p.IsActive() {
+			return sendError(c, errNotActive)
+		}
+		return h(c, svc)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
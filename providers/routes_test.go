@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Request Context ──────────────────────────────────────────────
+
+func TestRequestContextCanceledOnDeactivate(t *testing.T) {
+	p := NewMarkdownPlugin()
+	life, stop := context.WithCancel(context.Background())
+	p.life.Store(&lifetime{ctx: life, cancel: stop})
+
+	app := fiber.New()
+	app.Get("/", func(c fiber.Ctx) error {
+		ctx, cancel := p.requestContext(c)
+		defer cancel()
+		if ctx.Err() != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		stop()
+		select {
+		case <-ctx.Done():
+			return c.SendStatus(fiber.StatusNoContent)
+		case <-time.After(time.Second):
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+}
@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"errors"
+
+	"github.com/orchestra-mcp/markdown/src/schema"
+	"github.com/orchestra-mcp/markdown/src/service"
+)
+
+// Tool names.
+const (
+	toolRenderMarkdown      = "render_markdown"
+	toolRenderMarkdownBatch = "render_markdown_batch"
+	toolReloadConfig        = "reload_markdown_config"
+	toolExtractTOC          = "extract_toc"
+	toolExtractCodeBlocks   = "extract_code_blocks"
+
+	toolRenderMarkdownFile    = "render_markdown_file"
+	toolExtractTOCFile        = "extract_toc_file"
+	toolExtractCodeBlocksFile = "extract_code_blocks_file"
+)
+
+// renderProperties are the request fields shared by render_markdown and
+// batch items.
+func renderProperties() map[string]any {
+	return map[string]any{
+		"content":  schema.String("Markdown content to render"),
+		"format":   schema.Enum("Output format", schema.Formats...),
+		"raw_html": schema.Enum("Raw HTML handling; may only tighten the profile's mode, overrides options.raw_html", schema.RawHTMLModes...),
+		"profile":  schema.String("Named render profile, e.g. chat, docs, email, untrusted"),
+		"options":  schema.RenderOptions(),
+	}
+}
+
+// contentInput is the input of tools that take only markdown content.
+func contentInput() map[string]any {
+	return schema.Object("", map[string]any{
+		"content": schema.String("Markdown content"),
+	}, "content")
+}
+
+// pathProperty is the argument of the file tools.
+func pathProperty() map[string]any {
+	return schema.String("File path relative to the workspace root, slash-separated")
+}
+
+// toolInputSchemas returns the JSON Schema of each tool's arguments.
+func toolInputSchemas() map[string]map[string]any {
+	render := renderProperties()
+	render["previous_blocks"] = schema.Array(
+		"Block hashes from the previous render; with format blocks only changed blocks are returned",
+		schema.String("Block hash"))
+
+	item := renderProperties()
+	item["id"] = schema.String("Caller-chosen item identifier")
+
+	renderFile := renderProperties()
+	delete(renderFile, "content")
+	renderFile["path"] = pathProperty()
+	pathOnly := func() map[string]any {
+		return schema.Object("", map[string]any{"path": pathProperty()}, "path")
+	}
+
+	return map[string]map[string]any{
+		toolRenderMarkdown: schema.Object("", render, "content"),
+		toolRenderMarkdownBatch: schema.Object("", map[string]any{
+			"items": withMinItems(schema.Array("Documents to render", schema.Object("", item, "content")), 1),
+		}, "items"),
+		toolReloadConfig:      schema.Object("", map[string]any{}),
+		toolExtractTOC:        contentInput(),
+		toolExtractCodeBlocks: contentInput(),
+
+		toolRenderMarkdownFile:    schema.Object("", renderFile, "path"),
+		toolExtractTOCFile:        pathOnly(),
+		toolExtractCodeBlocksFile: pathOnly(),
+	}
+}
+
+// ToolOutputSchemas returns the JSON Schema of each tool's result, keyed
+// by tool name, for hosts that advertise MCP output schemas.
+func (p *MarkdownPlugin) ToolOutputSchemas() map[string]map[string]any {
+	return map[string]map[string]any{
+		toolRenderMarkdown: schema.RenderResult(),
+		toolRenderMarkdownBatch: schema.Object("Batch results in request order", map[string]any{
+			"results": schema.Array("One result per item", schema.BatchItemResult()),
+		}, "results"),
+		toolReloadConfig: schema.Map("Effective plugin config", map[string]any{}),
+		toolExtractTOC: schema.Object("Table of contents", map[string]any{
+			"toc": schema.Array("Headings in document order", schema.TOCEntry()),
+		}, "toc"),
+		toolExtractCodeBlocks: schema.Object("Fenced code blocks", map[string]any{
+			"code_blocks": schema.Array("Blocks in document order", schema.CodeBlock()),
+		}, "code_blocks"),
+
+		toolRenderMarkdownFile: schema.RenderResult(),
+		toolExtractTOCFile: schema.Object("Table of contents of a workspace file", map[string]any{
+			"toc":  schema.Array("Headings in document order", schema.TOCEntry()),
+			"file": schema.FileInfo(),
+		}, "toc", "file"),
+		toolExtractCodeBlocksFile: schema.Object("Fenced code blocks of a workspace file", map[string]any{
+			"code_blocks": schema.Array("Blocks in document order", schema.CodeBlock()),
+			"file":        schema.FileInfo(),
+		}, "code_blocks", "file"),
+	}
+}
+
+// withMinItems sets the minimum length of an array schema.
+func withMinItems(s map[string]any, n int) map[string]any {
+	s["minItems"] = n
+	return s
+}
+
+// validated rejects input that does not match s before h runs.
+func validated(s map[string]any, h func(map[string]any) (any, error)) func(map[string]any) (any, error) {
+	return func(input map[string]any) (any, error) {
+		if input == nil {
+			input = map[string]any{}
+		}
+		if err := schema.Validate(s, input); err != nil {
+			return nil, invalidArguments(err)
+		}
+		return h(input)
+	}
+}
+
+// invalidArguments reports tool arguments rejected by their schema.
+func invalidArguments(err error) error {
+	e := &service.Error{Code: service.CodeInvalidInput, Message: "invalid arguments: " + err.Error(), Err: err}
+	var ve *schema.ValidationError
+	if errors.As(err, &ve) {
+		e.Details = map[string]any{"errors": ve.Errors}
+	}
+	return e
+}
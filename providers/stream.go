@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// streamIdleTimeout is how long an untouched stream session is kept.
+const streamIdleTimeout = 10 * time.Minute
+
+// streamSweepInterval is how often idle sessions are looked for.
+const streamSweepInterval = time.Minute
+
+// streamSession is one incremental render with its SSE subscribers.
+type streamSession struct {
+	stream  *service.Stream
+	subs    map[chan *types.StreamUpdate]bool
+	touched time.Time
+}
+
+// streamRegistry tracks the open incremental render sessions.
+type streamRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*streamSession
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{sessions: make(map[string]*streamSession)}
+}
+
+// create registers a new session and returns its ID. It fails with
+// too_many_streams when max sessions are already open; zero means no
+// limit.
+func (r *streamRegistry) create(stream *service.Stream, max int) (string, error) {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if max > 0 && len(r.sessions) >= max {
+		return "", tooManyStreams(max)
+	}
+	r.sessions[id] = &streamSession{
+		stream:  stream,
+		subs:    make(map[chan *types.StreamUpdate]bool),
+		touched: time.Now(),
+	}
+	return id, nil
+}
+
+// get returns the session for id and marks it as used.
+func (r *streamRegistry) get(id string) (*streamSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if ok {
+		s.touched = time.Now()
+	}
+	return s, ok
+}
+
+// subscribe returns a channel receiving the session's updates, primed
+// with a snapshot of the blocks rendered so far.
+func (r *streamRegistry) subscribe(id string) (chan *types.StreamUpdate, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	ch := make(chan *types.StreamUpdate, 256)
+	ch <- s.stream.Snapshot()
+	s.subs[ch] = true
+	return ch, true
+}
+
+// unsubscribe detaches ch from the session if it is still attached.
+func (r *streamRegistry) unsubscribe(id string, ch chan *types.StreamUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok && s.subs[ch] {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends update to every subscriber. Subscribers that cannot keep
+// up are dropped; they can reconnect and receive a fresh snapshot.
+func (r *streamRegistry) publish(id string, update *types.StreamUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- update:
+		default:
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+	if update.Done {
+		r.closeLocked(id)
+	}
+}
+
+// sweep closes the sessions left untouched for streamIdleTimeout as of
+// now.
+func (r *streamRegistry) sweep(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.sessions {
+		if now.Sub(s.touched) > streamIdleTimeout {
+			r.closeLocked(id)
+		}
+	}
+}
+
+// sweepEvery sweeps idle sessions every interval until ctx is done.
+func (r *streamRegistry) sweepEvery(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			r.sweep(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// closeAll closes every session and its subscribers.
+func (r *streamRegistry) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range r.sessions {
+		r.closeLocked(id)
+	}
+}
+
+// closeLocked removes a session and closes its subscribers.
+func (r *streamRegistry) closeLocked(id string) {
+	for ch := range r.sessions[id].subs {
+		close(ch)
+	}
+	delete(r.sessions, id)
+}
+
+func (p *MarkdownPlugin) handleStreamCreate(c fiber.Ctx, svc *service.MarkdownService) error {
+	var body struct {
+		Options *types.RenderOptions `json:"options,omitempty"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.Bind().JSON(&body); err != nil {
+			return sendError(c, invalidBody(err))
+		}
+	}
+
+	var opts types.RenderOptions
+	if body.Options != nil {
+		opts = *body.Options
+	}
+	if err := svc.CheckRequest(types.RenderRequest{Options: opts}); err != nil {
+		return sendError(c, err)
+	}
+	id, err := p.streams.create(svc.NewStream(opts), p.Config().MaxStreams)
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": id})
+}
+
+func (p *MarkdownPlugin) handleStreamAppend(c fiber.Ctx, svc *service.MarkdownService) error {
+	id := c.Params("id")
+	session, ok := p.streams.get(id)
+	if !ok {
+		return sendError(c, streamNotFound(id))
+	}
+
+	var body struct {
+		Chunk string `json:"chunk"`
+		Done  bool   `json:"done"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return sendError(c, invalidBody(err))
+	}
+
+	ctx, cancel := p.requestContext(c)
+	defer cancel()
+	update, err := session.stream.Append(ctx, body.Chunk)
+	if err == nil && body.Done {
+		var closing *types.StreamUpdate
+		if closing, err = session.stream.Close(ctx); err == nil {
+			update.Patches = append(update.Patches, closing.Patches...)
+			update.Blocks, update.Done = closing.Blocks, true
+		}
+	}
+	if err != nil {
+		return sendError(c, err)
+	}
+
+	p.streams.publish(id, update)
+	return c.JSON(update)
+}
+
+func (p *MarkdownPlugin) handleStreamEvents(c fiber.Ctx, svc *service.MarkdownService) error {
+	id := c.Params("id")
+	ch, ok := p.streams.subscribe(id)
+	if !ok {
+		return sendError(c, streamNotFound(id))
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer p.streams.unsubscribe(id, ch)
+		for update := range ch {
+			data, _ := json.Marshal(update)
+			fmt.Fprintf(w, "event: patch\ndata: %s\n\n", data)
+			if err := w.Flush(); err != nil || update.Done {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Stream Sessions ──────────────────────────────────────────────
+
+func TestStreamRegistryLimitsSessions(t *testing.T) {
+	svc := service.New(parser.New(types.RenderOptions{}), false, 0)
+	r := newStreamRegistry()
+
+	_, err := r.create(svc.NewStream(types.RenderOptions{}), 2)
+	require.NoError(t, err)
+	_, err = r.create(svc.NewStream(types.RenderOptions{}), 2)
+	require.NoError(t, err)
+
+	_, err = r.create(svc.NewStream(types.RenderOptions{}), 2)
+	require.Error(t, err)
+	assert.Equal(t, codeTooManyStreams, service.AsError(err).Code)
+	assert.Equal(t, 429, errorStatus(codeTooManyStreams))
+
+	// Sessions closed by the idle sweep free their slots.
+	r.sweep(time.Now().Add(2 * streamIdleTimeout))
+	_, err = r.create(svc.NewStream(types.RenderOptions{}), 2)
+	assert.NoError(t, err)
+
+	for range 5 {
+		_, err = r.create(svc.NewStream(types.RenderOptions{}), 0)
+		require.NoError(t, err)
+	}
+}
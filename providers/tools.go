@@ -1,84 +1,174 @@
 package providers
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
 
 	"github.com/orchestra-mcp/framework/app/plugins"
+	"github.com/orchestra-mcp/markdown/src/service"
 	"github.com/orchestra-mcp/markdown/src/types"
 )
 
-// McpTools returns MCP tool definitions contributed by the Markdown plugin.
+// McpTools returns MCP tool definitions contributed by the Markdown
+// plugin. Arguments are validated against each tool's input schema
+// before its handler runs.
 func (p *MarkdownPlugin) McpTools() []plugins.McpToolDefinition {
+	inputs := toolInputSchemas()
+	tool := func(name, description string, h func(map[string]any) (any, error)) plugins.McpToolDefinition {
+		return plugins.McpToolDefinition{
+			Name:        name,
+			Description: description,
+			InputSchema: inputs[name],
+			Handler:     validated(inputs[name], h),
+		}
+	}
+
 	return []plugins.McpToolDefinition{
-		{
-			Name:        "render_markdown",
-			Description: "Render markdown content to HTML",
-			InputSchema: map[string]any{
-				"content": map[string]any{"type": "string", "description": "Markdown content to render"},
-				"format":  map[string]any{"type": "string", "description": "Output format: html, text, ast"},
-			},
-			Handler: p.toolRenderMarkdown,
-		},
-		{
-			Name:        "extract_toc",
-			Description: "Extract table of contents from markdown",
-			InputSchema: map[string]any{
-				"content": map[string]any{"type": "string", "description": "Markdown content"},
-			},
-			Handler: p.toolExtractTOC,
-		},
-		{
-			Name:        "extract_code_blocks",
-			Description: "Extract fenced code blocks from markdown",
-			InputSchema: map[string]any{
-				"content": map[string]any{"type": "string", "description": "Markdown content"},
-			},
-			Handler: p.toolExtractCodeBlocks,
-		},
+		tool(toolRenderMarkdown, "Render markdown content to HTML",
+			p.enabledTool(p.toolRenderMarkdown)),
+		tool(toolRenderMarkdownBatch, "Render many markdown documents to HTML in parallel",
+			p.enabledTool(p.toolRenderMarkdownBatch)),
+		tool(toolReloadConfig, "Reload the markdown plugin config and return the effective config",
+			p.toolReloadConfig),
+		tool(toolExtractTOC, "Extract table of contents from markdown",
+			p.enabledTool(p.toolExtractTOC)),
+		tool(toolExtractCodeBlocks, "Extract fenced code blocks from markdown",
+			p.enabledTool(p.toolExtractCodeBlocks)),
+		tool(toolRenderMarkdownFile, "Render a markdown file from the workspace to HTML",
+			p.enabledTool(p.toolRenderMarkdownFile)),
+		tool(toolExtractTOCFile, "Extract the table of contents of a markdown file in the workspace",
+			p.enabledTool(p.toolExtractTOCFile)),
+		tool(toolExtractCodeBlocksFile, "Extract fenced code blocks from a markdown file in the workspace",
+			p.enabledTool(p.toolExtractCodeBlocksFile)),
+	}
+}
+
+// enabledTool guards a tool handler so it fails until the plugin is
+// active and enabled, handing it the service current at call time.
+func (p *MarkdownPlugin) enabledTool(h func(*service.MarkdownService, map[string]any) (any, error)) func(map[string]any) (any, error) {
+	return func(input map[string]any) (any, error) {
+		svc := p.Service()
+		if svc == nil || !p.IsActive() {
+			return nil, errNotActive
+		}
+		return h(svc, input)
+	}
+}
+
+// toolContext returns the context for MCP tool calls. The framework
+// hands tool handlers no request context, so calls are bounded by the
+// service's render budget and canceled when the plugin deactivates.
+func (p *MarkdownPlugin) toolContext() context.Context {
+	if life := p.life.Load(); life != nil {
+		return life.ctx
 	}
+	return context.Background()
 }
 
-func (p *MarkdownPlugin) toolRenderMarkdown(input map[string]any) (any, error) {
+func (p *MarkdownPlugin) toolRenderMarkdown(svc *service.MarkdownService, input map[string]any) (any, error) {
 	content, _ := input["content"].(string)
 	if content == "" {
-		return nil, fmt.Errorf("content is required")
+		return nil, missingField("content")
 	}
 
-	format, _ := input["format"].(string)
-	req := types.RenderRequest{Content: content, Format: format}
-
-	result, err := p.svc.Render(req)
+	req, err := renderRequest(input)
 	if err != nil {
 		return nil, err
 	}
+	req.PreviousBlocks = stringSlice(input["previous_blocks"])
+
+	result, err := svc.RenderContext(p.toolContext(), req)
+	if err != nil {
+		return nil, toolError(err)
+	}
 
 	return result, nil
 }
 
-func (p *MarkdownPlugin) toolExtractTOC(input map[string]any) (any, error) {
+func (p *MarkdownPlugin) toolRenderMarkdownBatch(svc *service.MarkdownService, input map[string]any) (any, error) {
+	raw, _ := input["items"].([]any)
+	if len(raw) == 0 {
+		return nil, missingField("items")
+	}
+
+	items := make([]types.BatchItem, 0, len(raw))
+	for _, r := range raw {
+		m, _ := r.(map[string]any)
+		id, _ := m["id"].(string)
+		req, err := renderRequest(m)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, types.BatchItem{ID: id, RenderRequest: req})
+	}
+
+	results, err := svc.RenderBatch(p.toolContext(), items)
+	if err != nil {
+		return nil, toolError(err)
+	}
+
+	return map[string]any{"results": results}, nil
+}
+
+func (p *MarkdownPlugin) toolExtractTOC(svc *service.MarkdownService, input map[string]any) (any, error) {
 	content, _ := input["content"].(string)
 	if content == "" {
-		return nil, fmt.Errorf("content is required")
+		return nil, missingField("content")
 	}
 
-	toc, err := p.svc.ExtractTOC(content)
+	toc, err := svc.ExtractTOCContext(p.toolContext(), content)
 	if err != nil {
-		return nil, err
+		return nil, toolError(err)
 	}
 
 	return map[string]any{"toc": toc}, nil
 }
 
-func (p *MarkdownPlugin) toolExtractCodeBlocks(input map[string]any) (any, error) {
+func (p *MarkdownPlugin) toolExtractCodeBlocks(svc *service.MarkdownService, input map[string]any) (any, error) {
 	content, _ := input["content"].(string)
 	if content == "" {
-		return nil, fmt.Errorf("content is required")
+		return nil, missingField("content")
 	}
 
-	blocks, err := p.svc.ExtractCodeBlocks(content)
+	blocks, err := svc.ExtractCodeBlocksContext(p.toolContext(), content)
 	if err != nil {
-		return nil, err
+		return nil, toolError(err)
 	}
 
 	return map[string]any{"code_blocks": blocks}, nil
 }
+
+// renderRequest builds a render request from tool arguments. A
+// top-level raw_html overrides options.raw_html.
+func renderRequest(input map[string]any) (types.RenderRequest, error) {
+	req := types.RenderRequest{}
+	req.Content, _ = input["content"].(string)
+	req.Format, _ = input["format"].(string)
+	req.Profile, _ = input["profile"].(string)
+
+	if opts, ok := input["options"]; ok {
+		raw, err := json.Marshal(opts)
+		if err == nil {
+			err = json.Unmarshal(raw, &req.Options)
+		}
+		if err != nil {
+			return req, invalidArguments(err)
+		}
+	}
+	if rawHTML, _ := input["raw_html"].(string); rawHTML != "" {
+		req.Options.RawHTML = rawHTML
+	}
+	return req, nil
+}
+
+// stringSlice converts a JSON array of strings from tool input.
+func stringSlice(v any) []string {
+	raw, _ := v.([]any)
+	out := make([]string, 0, len(raw))
+	for _, r := range raw {
+		if s, ok := r.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
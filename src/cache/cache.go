@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats reports cache effectiveness counters.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Capacity  int    `json:"capacity"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+}
+
+// LRU is a size-bounded, TTL-expiring least-recently-used cache. It is
+// safe for concurrent use.
+type LRU[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	maxBytes int64
+	size     func(V) int64
+	ttl      time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+	bytes    int64
+	stats    Stats
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	size    int64
+	expires time.Time
+}
+
+// New creates an LRU holding at most capacity entries, each valid for
+// ttl. A zero ttl keeps entries until they are evicted.
+func New[V any](capacity int, ttl time.Duration) *LRU[V] {
+	return NewSized[V](capacity, 0, ttl, nil)
+}
+
+// NewSized creates an LRU like New that also holds at most maxBytes, as
+// measured by size. Values larger than maxBytes are not cached. A zero
+// maxBytes or nil size leaves only the entry bound.
+func NewSized[V any](capacity int, maxBytes int64, ttl time.Duration, size func(V) int64) *LRU[V] {
+	if size == nil {
+		maxBytes = 0
+	}
+	return &LRU[V]{
+		capacity: capacity,
+		maxBytes: maxBytes,
+		size:     size,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key and marks it recently used.
+// Expired entries are dropped and counted as evictions.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		if c.ttl <= 0 || time.Now().Before(e.expires) {
+			c.ll.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.remove(el)
+		c.stats.Evictions++
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Put stores value under key, evicting least recently used entries
+// while the cache holds too many entries or bytes.
+func (c *LRU[V]) Put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	if c.maxBytes > 0 {
+		size = c.size(value)
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry[V]{key: key, value: value, size: size, expires: time.Now().Add(c.ttl)})
+	c.bytes += size
+	for c.ll.Len() > c.capacity || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LRU[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Entries = c.ll.Len()
+	s.Capacity = c.capacity
+	s.Bytes = c.bytes
+	s.MaxBytes = c.maxBytes
+	return s
+}
+
+func (c *LRU[V]) remove(el *list.Element) {
+	e := el.Value.(*entry[V])
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
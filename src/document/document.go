@@ -0,0 +1,115 @@
+// Package document wraps rendered markdown in a standalone HTML page
+// with its own stylesheet, so it can be previewed or saved as a single
+// file.
+package document
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// stylesheet is the page layout and typography, with light and dark
+// color schemes.
+//
+//go:embed style.css
+var stylesheet string
+
+var page = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html{{with .Lang}} lang="{{.}}"{{end}}>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="color-scheme" content="light dark">
+<title>{{.Title}}</title>
+{{- with .Description}}
+<meta name="description" content="{{.}}">
+{{- end}}
+<style>
+{{.Stylesheet}}
+</style>
+<style>
+{{.ThemeCSS}}
+</style>
+</head>
+<body{{if .TOC}} class="has-toc"{{end}}>
+{{- if .TOC}}
+<nav class="toc" aria-label="Table of contents">
+<ul>
+{{- range .TOC}}
+<li class="toc-level-{{.Level}}"><a href="#{{.ID}}">{{.Text}}</a></li>
+{{- end}}
+</ul>
+</nav>
+{{- end}}
+<main class="markdown-body">
+{{.Body}}
+</main>
+</body>
+</html>
+`))
+
+// Page is the content of a standalone document.
+type Page struct {
+	Title       string
+	Description string
+	// Lang is the BCP 47 language of the page; empty omits it.
+	Lang string
+	// Body is the rendered HTML, already sanitized where required. Code
+	// must be highlighted with classes for CodeTheme to apply.
+	Body string
+	// TOC entries are listed in a sidebar; empty omits it.
+	TOC       []types.TOCEntry
+	CodeTheme string
+}
+
+// Render returns page as a complete HTML document.
+func Render(p Page) (string, error) {
+	var buf bytes.Buffer
+	err := page.Execute(&buf, struct {
+		Page
+		Body       template.HTML
+		Stylesheet template.CSS
+		ThemeCSS   template.CSS
+	}{
+		Page:       p,
+		Body:       template.HTML(p.Body),
+		Stylesheet: template.CSS(stylesheet),
+		ThemeCSS:   template.CSS(ThemeCSS(p.CodeTheme)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ThemeCSS returns the stylesheet of a chroma style for code highlighted
+// with classes. Empty means monokai, the parser's default; unknown names
+// get chroma's fallback style.
+func ThemeCSS(theme string) string {
+	if theme == "" {
+		theme = "monokai"
+	}
+	var buf strings.Builder
+	_ = chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, styles.Get(theme))
+	return buf.String()
+}
+
+// Title picks the page title: the frontmatter title, else the first
+// top-level heading, else fallback.
+func Title(meta map[string]string, toc []types.TOCEntry, fallback string) string {
+	if title := strings.TrimSpace(meta["title"]); title != "" {
+		return title
+	}
+	for _, e := range toc {
+		if e.Level == 1 {
+			return e.Text
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,324 @@
+// Package openapi describes the markdown REST routes as an OpenAPI 3.1
+// document. Bodies use the JSON Schemas of src/schema, which are checked
+// against the types in src/types.
+package openapi
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/orchestra-mcp/markdown/src/schema"
+)
+
+// Version is the OpenAPI version of the document. 3.1 takes JSON Schema
+// as is.
+const Version = "3.1.0"
+
+// Media types of non-JSON bodies.
+const (
+	mimeJSON        = "application/json"
+	mimeMarkdown    = "text/markdown"
+	mimeHTML        = "text/html"
+	mimeText        = "text/plain"
+	mimeEventStream = "text/event-stream"
+)
+
+// errorStatuses describes the causes behind each error status.
+var errorStatuses = map[int]string{
+	400: "Invalid body, arguments, options or path: invalid_input, invalid_options, unsupported_format, invalid_path or invalid_config",
+	404: "Unknown stream or file: not_found",
+	406: "The Accept header or format cannot be produced: not_acceptable",
+	409: "The stream is finished: stream_closed",
+	413: "Input or batch too large: input_too_large or batch_too_large",
+	415: "Multipart or malformed Content-Type: unsupported_media_type",
+	429: "Too many open stream sessions: too_many_streams",
+	422: "Nesting depth or node limit exceeded: limit_exceeded",
+	499: "The client went away: canceled",
+	500: "Unexpected failure: internal",
+	503: "The plugin is disabled or not activated: unavailable",
+	504: "The render budget was spent: timeout",
+}
+
+// Components returns the named schemas of the document.
+func Components() map[string]map[string]any {
+	return map[string]map[string]any{
+		"RenderRequest":   schema.RenderRequest(),
+		"RenderOptions":   schema.RenderOptions(),
+		"SanitizePolicy":  schema.SanitizePolicy(),
+		"Limits":          schema.Limits(),
+		"RenderResult":    schema.RenderResult(),
+		"FileInfo":        schema.FileInfo(),
+		"TOCEntry":        schema.TOCEntry(),
+		"CodeBlock":       schema.CodeBlock(),
+		"Truncation":      schema.Truncation(),
+		"Diagnostic":      schema.Diagnostic(),
+		"BlockRef":        schema.BlockRef(),
+		"BlockOp":         schema.BlockOp(),
+		"BatchItem":       schema.BatchItem(),
+		"BatchItemResult": schema.BatchItemResult(),
+		"BlockPatch":      schema.BlockPatch(),
+		"StreamUpdate":    schema.StreamUpdate(),
+		"CacheStats":      schema.CacheStats(),
+		"Error":           schema.Error(),
+	}
+}
+
+// Document returns the OpenAPI document of the routes mounted under
+// serverURL, e.g. "/api", for the plugin at version. Nested schemas that
+// equal a component are replaced by references to it.
+func Document(version, serverURL string) map[string]any {
+	components := Components()
+	schemas := make(map[string]any, len(components))
+	for name, s := range components {
+		schemas[name] = withRefs(s, components, name)
+	}
+
+	return map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":       "Orchestra Markdown",
+			"version":     version,
+			"description": "Markdown rendering, extraction and streaming routes of the Orchestra markdown plugin.",
+		},
+		"servers":    []any{map[string]any{"url": serverURL}},
+		"paths":      withRefs(paths(), components, ""),
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// paths describes every route.
+func paths() map[string]any {
+	renderQuery := []any{
+		query("format", "Output format, for markdown bodies", schema.Enum("Output format", schema.Formats...)),
+		query("profile", "Named render profile, for markdown bodies", schema.String("Profile name")),
+		query("raw_html", "Raw HTML handling, for markdown bodies", schema.Enum("Raw HTML mode", schema.RawHTMLModes...)),
+	}
+	renderBody := map[string]any{
+		"required": true,
+		"content": map[string]any{
+			mimeJSON:     media(ref("RenderRequest")),
+			mimeMarkdown: media(markdownText()),
+			mimeText:     media(markdownText()),
+		},
+	}
+	contentBody := jsonBody(schema.Object("", map[string]any{
+		"content": schema.String("Markdown content"),
+	}, "content"))
+	streamID := map[string]any{
+		"name": "id", "in": "path", "required": true,
+		"schema": schema.String("Stream session ID"),
+	}
+	page := map[string]any{mimeHTML: media(schema.String("Standalone HTML document"))}
+
+	return map[string]any{
+		"/markdown/render": map[string]any{
+			"post": operation("renderMarkdown", "Render markdown",
+				"Renders a JSON request, or a raw markdown body of any other type with format, profile and raw_html in query parameters. "+
+					"Accept selects the full result, the HTML fragment or the plain text.",
+				map[string]any{
+					"parameters": append(renderQuery, map[string]any{
+						"name": "If-None-Match", "in": "header",
+						"schema": schema.String("ETag of a previous response"),
+					}),
+					"requestBody": renderBody,
+				},
+				responses(map[string]any{
+					"200": map[string]any{
+						"description": "Rendered document",
+						"headers": map[string]any{
+							"ETag": map[string]any{"schema": schema.String("Content address of the result")},
+						},
+						"content": map[string]any{
+							mimeJSON: media(ref("RenderResult")),
+							mimeHTML: media(schema.String("HTML fragment, or the page for format document")),
+							mimeText: media(schema.String("Plain-text rendering")),
+						},
+					},
+					"304": map[string]any{"description": "The result matches If-None-Match"},
+				}, 400, 406, 413, 415, 422, 499, 503, 504)),
+		},
+		"/markdown/render/batch": map[string]any{
+			"post": operation("renderMarkdownBatch", "Render many documents",
+				"Renders every item in parallel. Item failures are reported per item.",
+				map[string]any{"requestBody": jsonBody(schema.Object("", map[string]any{
+					"items": schema.Array("Documents to render", ref("BatchItem")),
+				}, "items"))},
+				responses(map[string]any{
+					"200": jsonResponse("Results in request order", schema.Object("", map[string]any{
+						"results": schema.Array("One result per item", ref("BatchItemResult")),
+					}, "results")),
+				}, 400, 413, 499, 503)),
+		},
+		"/markdown/render/stream": map[string]any{
+			"post": operation("renderMarkdownStream", "Render a large document",
+				"Renders a raw markdown body and streams the HTML back block by block. "+
+					"The whole body is bounded by max_stream_size and stream_timeout_ms. "+
+					"A block that fails after the first ends the body with a <!-- render-error {...} --> comment holding the error body.",
+				map[string]any{
+					"parameters":  renderQuery[2:],
+					"requestBody": map[string]any{"required": true, "content": map[string]any{mimeMarkdown: media(markdownText())}},
+				},
+				responses(map[string]any{
+					"200": map[string]any{"description": "HTML, one block at a time", "content": map[string]any{mimeHTML: media(schema.String("HTML"))}},
+				}, 400, 413, 422, 499, 503, 504)),
+		},
+		"/markdown/preview": map[string]any{
+			"get": operation("previewMarkdownFile", "Preview a workspace file",
+				"Renders a file under the workspace root as a standalone HTML page.",
+				map[string]any{"parameters": append([]any{
+					map[string]any{
+						"name": "path", "in": "query", "required": true,
+						"schema": schema.String("File path relative to the workspace root, slash-separated"),
+					},
+				}, renderQuery[1:]...)},
+				responses(map[string]any{
+					"200": map[string]any{"description": "Standalone HTML document", "content": page},
+				}, 400, 404, 413, 422, 499, 503, 504)),
+			"post": operation("previewMarkdown", "Preview markdown",
+				"Renders a JSON request or raw markdown body as a standalone HTML page.",
+				map[string]any{"parameters": renderQuery[1:], "requestBody": renderBody},
+				responses(map[string]any{
+					"200": map[string]any{"description": "Standalone HTML document", "content": page},
+				}, 400, 413, 415, 422, 499, 503, 504)),
+		},
+		"/markdown/toc": map[string]any{
+			"post": operation("extractTOC", "Extract the table of contents", "",
+				map[string]any{"requestBody": contentBody},
+				responses(map[string]any{
+					"200": jsonResponse("Table of contents", schema.Object("", map[string]any{
+						"toc": schema.Array("Headings in document order", ref("TOCEntry")),
+					}, "toc")),
+				}, 400, 413, 499, 503, 504)),
+		},
+		"/markdown/code-blocks": map[string]any{
+			"post": operation("extractCodeBlocks", "Extract fenced code blocks", "",
+				map[string]any{"requestBody": contentBody},
+				responses(map[string]any{
+					"200": jsonResponse("Fenced code blocks", schema.Object("", map[string]any{
+						"code_blocks": schema.Array("Blocks in document order", ref("CodeBlock")),
+					}, "code_blocks")),
+				}, 400, 413, 499, 503, 504)),
+		},
+		"/markdown/cache": map[string]any{
+			"get": operation("getCacheStats", "Render cache statistics", "", nil,
+				responses(map[string]any{"200": jsonResponse("Cache counters", ref("CacheStats"))}, 503)),
+		},
+		"/markdown/config": map[string]any{
+			"get": operation("getConfig", "Effective plugin config", "", nil,
+				responses(map[string]any{"200": jsonResponse("Config keys and values", config())}, 503)),
+		},
+		"/markdown/config/reload": map[string]any{
+			"post": operation("reloadConfig", "Reload the plugin config",
+				"Reloads the config and swaps in a new service. An invalid config is rejected and the current one kept.", nil,
+				responses(map[string]any{"200": jsonResponse("Config keys and values", config())}, 400, 503)),
+		},
+		"/markdown/stream": map[string]any{
+			"post": operation("createStream", "Start an incremental render session", "",
+				map[string]any{"requestBody": map[string]any{"content": map[string]any{mimeJSON: media(schema.Object("", map[string]any{
+					"options": ref("RenderOptions"),
+				}))}}},
+				responses(map[string]any{
+					"201": jsonResponse("Session created", schema.Object("", map[string]any{
+						"id": schema.String("Stream session ID"),
+					}, "id")),
+				}, 400, 429, 503)),
+		},
+		"/markdown/stream/{id}": map[string]any{
+			"post": operation("appendStream", "Append a chunk to a session",
+				"Returns the block patches for the chunk. done finishes the session.",
+				map[string]any{
+					"parameters": []any{streamID},
+					"requestBody": jsonBody(schema.Object("", map[string]any{
+						"chunk": schema.String("Markdown to append"),
+						"done":  schema.Boolean("Finish the session after this chunk"),
+					})),
+				},
+				responses(map[string]any{"200": jsonResponse("Changed blocks", ref("StreamUpdate"))}, 400, 404, 409, 413, 422, 499, 503, 504)),
+			"get": operation("streamEvents", "Subscribe to a session",
+				"Server-sent patch events, each with a StreamUpdate as data, until the session is done.",
+				map[string]any{"parameters": []any{streamID}},
+				responses(map[string]any{
+					"200": map[string]any{"description": "patch events", "content": map[string]any{mimeEventStream: media(schema.String("Event stream"))}},
+				}, 404, 503)),
+		},
+		"/markdown/openapi.json": map[string]any{
+			"get": operation("getOpenAPI", "This document", "", nil,
+				responses(map[string]any{"200": jsonResponse("OpenAPI document", map[string]any{"type": "object"})})),
+		},
+	}
+}
+
+// operation returns an operation object with the extra fields in more.
+func operation(id, summary, description string, more, responses map[string]any) map[string]any {
+	op := map[string]any{"operationId": id, "summary": summary, "tags": []string{"markdown"}, "responses": responses}
+	if description != "" {
+		op["description"] = description
+	}
+	maps.Copy(op, more)
+	return op
+}
+
+// responses adds an Error response for each status to ok.
+func responses(ok map[string]any, statuses ...int) map[string]any {
+	for _, status := range statuses {
+		ok[strconv.Itoa(status)] = jsonResponse(errorStatuses[status], ref("Error"))
+	}
+	return ok
+}
+
+// jsonResponse returns a response with a JSON body matching s.
+func jsonResponse(description string, s map[string]any) map[string]any {
+	return map[string]any{"description": description, "content": map[string]any{mimeJSON: media(s)}}
+}
+
+// jsonBody returns a required JSON request body matching s.
+func jsonBody(s map[string]any) map[string]any {
+	return map[string]any{"required": true, "content": map[string]any{mimeJSON: media(s)}}
+}
+
+func media(s map[string]any) map[string]any {
+	return map[string]any{"schema": s}
+}
+
+func query(name, description string, s map[string]any) map[string]any {
+	return map[string]any{"name": name, "in": "query", "description": description, "schema": s}
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func markdownText() map[string]any {
+	return schema.String("Markdown source")
+}
+
+func config() map[string]any {
+	return schema.Map("Effective plugin config keyed by config key", map[string]any{})
+}
+
+// withRefs returns a copy of v with every nested schema that equals a
+// component other than self replaced by a reference to it.
+func withRefs(v any, components map[string]map[string]any, self string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range slices.Sorted(maps.Keys(components)) {
+			if name != self && reflect.DeepEqual(v, components[name]) {
+				return ref(name)
+			}
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = withRefs(item, components, "")
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = withRefs(item, components, "")
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+var (
+	// refDefRe matches a link reference definition label.
+	refDefRe = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:`)
+	// refUseRe matches full and collapsed reference links.
+	refUseRe = regexp.MustCompile(`\[([^\]]*)\]\[([^\]]*)\]`)
+	// codeSpanRe matches inline code spans, whose text is never a link.
+	codeSpanRe = regexp.MustCompile("`[^`]*`")
+)
+
+// sourceLine is one line of the input outside fenced code.
+type sourceLine struct {
+	offset int
+	text   []byte
+}
+
+// diagnoser collects diagnostics for one document.
+type diagnoser struct {
+	src        []byte
+	lineStarts []int
+	diags      []types.Diagnostic
+	// fenceLangs are languages rendered by a registered fence renderer.
+	fenceLangs []string
+}
+
+// Diagnose reports problems in input that rendering tolerates silently:
+// malformed frontmatter, unclosed fences, duplicate heading IDs, unknown
+// code languages, broken reference links, and an unknown code theme.
+func (p *MarkdownParser) Diagnose(input []byte) []types.Diagnostic {
+	d := newDiagnoser(input)
+	d.fenceLangs = p.reg.Languages()
+
+	if theme := p.opts.CodeTheme; theme != "" {
+		if _, ok := styles.Registry[theme]; !ok {
+			d.diags = append(d.diags, types.Diagnostic{
+				Severity: types.SeverityWarning,
+				Code:     types.DiagUnknownCodeTheme,
+				Message:  fmt.Sprintf("unknown code theme %q; the default style is used", theme),
+			})
+		}
+	}
+
+	start := d.frontmatter()
+	lines := d.fences(start)
+	d.headings(lines)
+	d.references(lines)
+
+	sortDiagnostics(d.diags)
+	return d.diags
+}
+
+// newDiagnoser indexes the line starts of src.
+func newDiagnoser(src []byte) *diagnoser {
+	d := &diagnoser{src: src, lineStarts: []int{0}}
+	for i, c := range src {
+		if c == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	return d
+}
+
+// MergeDiagnostics combines two lists of diagnostics in source order.
+func MergeDiagnostics(a, b []types.Diagnostic) []types.Diagnostic {
+	if len(b) == 0 {
+		return a
+	}
+	merged := append(slices.Clone(a), b...)
+	sortDiagnostics(merged)
+	return merged
+}
+
+// sortDiagnostics orders diags by source offset, keeping the order of
+// diagnostics at the same offset.
+func sortDiagnostics(diags []types.Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		return offsetOf(diags[i]) < offsetOf(diags[j])
+	})
+}
+
+// frontmatter checks a leading --- block the way ExtractFrontmatter
+// reads it and returns the offset where the body starts.
+func (d *diagnoser) frontmatter() int {
+	s := string(d.src)
+	if !strings.HasPrefix(s, "---\n") {
+		return 0
+	}
+	end := strings.Index(s[4:], "\n---")
+	if end < 0 {
+		d.add(types.SeverityWarning, types.DiagFrontmatterUnclosed,
+			"frontmatter is never closed; it renders as a thematic break", 0, 3)
+		return 0
+	}
+
+	offset := 4
+	for _, line := range strings.Split(s[4:4+end], "\n") {
+		trimmed := strings.TrimSpace(line)
+		key, _, ok := strings.Cut(line, ":")
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && (!ok || strings.TrimSpace(key) == "") {
+			d.add(types.SeverityWarning, types.DiagFrontmatterMalformed,
+				fmt.Sprintf("frontmatter line %q is not a key: value pair and is ignored", trimmed),
+				offset, offset+len(line))
+		}
+		offset += len(line) + 1
+	}
+	return 4 + end + 4
+}
+
+// fences walks the lines from start, reporting unknown code languages and
+// unclosed fences, and returns the lines outside fenced code.
+func (d *diagnoser) fences(start int) []sourceLine {
+	var (
+		lines     []sourceLine
+		fence     string
+		fenceFrom int
+	)
+	for offset := start; offset < len(d.src); {
+		line := d.src[offset:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		next := offset + len(line) + 1
+
+		switch {
+		case fence != "":
+			if isFenceClose(string(line), fence) {
+				fence = ""
+			}
+		case fenceRe.Match(line):
+			m := fenceRe.FindSubmatch(line)
+			fence, fenceFrom = string(m[1]), offset
+			lang := fenceLanguage(string(line[len(m[0]):]))
+			if lang != "" && lexers.Get(lang) == nil && !d.knownLanguage(lang) {
+				d.add(types.SeverityInfo, types.DiagUnknownCodeLanguage,
+					fmt.Sprintf("unknown code language %q; the block is not highlighted", lang),
+					offset, offset+len(line))
+			}
+		default:
+			lines = append(lines, sourceLine{offset: offset, text: line})
+		}
+		offset = next
+	}
+	if fence != "" {
+		d.add(types.SeverityWarning, types.DiagUnclosedFence,
+			"code fence "+fence+" is never closed; it runs to the end of the document",
+			fenceFrom, len(d.src))
+	}
+	return lines
+}
+
+// specialLanguage reports fence languages handled outside highlighting.
+func specialLanguage(lang string) bool {
+	return lang == "mermaid" || lang == "math"
+}
+
+// knownLanguage reports whether lang is rendered without a lexer.
+func (d *diagnoser) knownLanguage(lang string) bool {
+	return specialLanguage(lang) || slices.Contains(d.fenceLangs, lang)
+}
+
+// headings reports headings whose TOC IDs collide with an earlier one.
+func (d *diagnoser) headings(lines []sourceLine) {
+	seen := make(map[string]bool)
+	for _, l := range lines {
+		m := headingRe.FindSubmatch(l.text)
+		if m == nil {
+			continue
+		}
+		id := slugify(strings.TrimSpace(string(m[2])))
+		if seen[id] {
+			d.add(types.SeverityWarning, types.DiagDuplicateHeadingID,
+				fmt.Sprintf("heading ID %q is already used; TOC links point to the first heading", id),
+				l.offset, l.offset+len(l.text))
+		}
+		seen[id] = true
+	}
+}
+
+// references reports full and collapsed reference links whose label has
+// no definition.
+func (d *diagnoser) references(lines []sourceLine) {
+	defined := make(map[string]bool)
+	for _, l := range lines {
+		if m := refDefRe.FindSubmatch(l.text); m != nil {
+			defined[normalizeLabel(string(m[1]))] = true
+		}
+	}
+
+	for _, l := range lines {
+		if refDefRe.Match(l.text) {
+			continue
+		}
+		text := codeSpanRe.ReplaceAllFunc(l.text, func(b []byte) []byte {
+			return bytes.Repeat([]byte(" "), len(b))
+		})
+		for _, m := range refUseRe.FindAllSubmatchIndex(text, -1) {
+			label := string(text[m[4]:m[5]])
+			if label == "" {
+				label = string(text[m[2]:m[3]])
+			}
+			if !defined[normalizeLabel(label)] {
+				d.add(types.SeverityWarning, types.DiagBrokenReferenceLink,
+					fmt.Sprintf("reference %q is not defined; the link renders as text", label),
+					l.offset+m[0], l.offset+m[1])
+			}
+		}
+	}
+}
+
+// normalizeLabel folds a reference label the way CommonMark matches them.
+func normalizeLabel(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// add records a diagnostic spanning the bytes [from, to).
+func (d *diagnoser) add(severity, code, msg string, from, to int) {
+	d.diags = append(d.diags, types.Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  msg,
+		Range:    &types.Range{Start: d.position(from), End: d.position(to)},
+	})
+}
+
+// position converts a byte offset into a line and column.
+func (d *diagnoser) position(offset int) types.Position {
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	return types.Position{Line: line + 1, Column: offset - d.lineStarts[line] + 1, Offset: offset}
+}
+
+// offsetOf orders diagnostics without a range first.
+func offsetOf(d types.Diagnostic) int {
+	if d.Range == nil {
+		return -1
+	}
+	return d.Range.Start.Offset
+}
@@ -0,0 +1,259 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"maps"
+	"strings"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// FenceBlock is a fenced code block handed to a FenceRenderer.
+type FenceBlock struct {
+	Language string
+	// Info is the full info string after the opening fence.
+	Info string
+	// Attributes holds the key=value pairs and {.class #id} shorthands
+	// that follow the language in the info string. Bare words map to "".
+	Attributes map[string]string
+	Code       []byte
+}
+
+// FenceRenderer renders a fenced code block of one language to HTML.
+// The HTML goes through the sanitizer like any other output. When it
+// returns an error the block is highlighted as code instead and the
+// error is reported as a fence_render_failed warning.
+type FenceRenderer func(block FenceBlock) (string, error)
+
+// builtinFences returns the fence renderers enabled by opts. Registered
+// renderers take precedence over these.
+func builtinFences(opts types.RenderOptions) map[string]FenceRenderer {
+	fences := make(map[string]FenceRenderer)
+	if opts.EnableMermaid {
+		fences["mermaid"] = wrapFence(`<pre class="mermaid">`, "</pre>\n")
+	}
+	if opts.EnableMath {
+		fences["math"] = wrapFence(`<div class="math math-display">`, "</div>\n")
+	}
+	return fences
+}
+
+// wrapFence renders the escaped code between open and close, leaving
+// the actual drawing to a client-side library.
+func wrapFence(open, close string) FenceRenderer {
+	return func(block FenceBlock) (string, error) {
+		return open + html.EscapeString(string(block.Code)) + close, nil
+	}
+}
+
+// fenceSet merges the built-in renderers for opts with the registered
+// ones.
+func fenceSet(opts types.RenderOptions, registered map[string]FenceRenderer) map[string]FenceRenderer {
+	fences := builtinFences(opts)
+	maps.Copy(fences, registered)
+	return fences
+}
+
+// renderFences runs the renderer for every fenced code block whose
+// language has one and swaps the block for the resulting HTML. Blocks
+// whose renderer fails stay code and are reported as warnings.
+func renderFences(ctx context.Context, doc ast.Node, source []byte, fences map[string]FenceRenderer) ([]types.Diagnostic, error) {
+	if len(fences) == 0 {
+		return nil, nil
+	}
+
+	var nodes []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fc, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if _, ok := fences[fenceLanguage(fenceInfo(fc, source))]; ok {
+				nodes = append(nodes, fc)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var d *diagnoser
+	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := fenceBlock(n, source)
+		out, err := callFence(fences[block.Language], block)
+		if err != nil {
+			if d == nil {
+				d = newDiagnoser(source)
+			}
+			from, to := fenceSpan(n, source)
+			d.add(types.SeverityWarning, types.DiagFenceRenderFailed,
+				fmt.Sprintf("%s block could not be rendered: %v; it is shown as code", block.Language, err),
+				from, to)
+			continue
+		}
+		n.Parent().ReplaceChild(n.Parent(), n, &fenceHTML{html: out})
+	}
+	if d == nil {
+		return nil, nil
+	}
+	return d.diags, nil
+}
+
+// callFence calls fn, turning a panic into an error so one faulty
+// renderer cannot take down the render.
+func callFence(fn FenceRenderer, block FenceBlock) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("renderer panicked: %v", r)
+		}
+	}()
+	return fn(block)
+}
+
+// fenceBlock collects the language, info string and code of n.
+func fenceBlock(n *ast.FencedCodeBlock, source []byte) FenceBlock {
+	info := fenceInfo(n, source)
+	block := FenceBlock{Language: fenceLanguage(info), Info: info}
+	block.Attributes = fenceAttributes(strings.TrimPrefix(strings.TrimSpace(info), block.Language))
+
+	var code bytes.Buffer
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		code.Write(line.Value(source))
+	}
+	block.Code = code.Bytes()
+	return block
+}
+
+// fenceSpan returns the source range of n from its opening fence line to
+// the end of its last code line.
+func fenceSpan(n *ast.FencedCodeBlock, source []byte) (int, int) {
+	from, to := 0, len(source)
+	if n.Info != nil {
+		from = bytes.LastIndexByte(source[:n.Info.Segment.Start], '\n') + 1
+		to = n.Info.Segment.Stop
+	}
+	if lines := n.Lines(); lines.Len() > 0 {
+		to = lines.At(lines.Len() - 1).Stop
+	}
+	return from, to
+}
+
+// fenceInfo returns the info string of n, or "" when it has none.
+func fenceInfo(n *ast.FencedCodeBlock, source []byte) string {
+	if n.Info == nil {
+		return ""
+	}
+	return string(n.Info.Value(source))
+}
+
+// fenceLanguage returns the language named by an info string: its first
+// word, without an attribute group such as {.class #id} attached to it.
+func fenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	lang, _, _ := strings.Cut(fields[0], "{")
+	return lang
+}
+
+// fenceAttributes parses the attributes that follow the language in an
+// info string: key=value and key="quoted value" pairs, bare words, and
+// an optional {.class #id key=value} group. Classes accumulate in
+// "class".
+func fenceAttributes(s string) map[string]string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}"))
+	if s == "" {
+		return nil
+	}
+
+	attrs := make(map[string]string)
+	for _, tok := range splitInfo(s) {
+		switch {
+		case strings.HasPrefix(tok, "."):
+			attrs["class"] = strings.TrimSpace(attrs["class"] + " " + tok[1:])
+		case strings.HasPrefix(tok, "#"):
+			attrs["id"] = tok[1:]
+		default:
+			key, value, _ := strings.Cut(tok, "=")
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			if key != "" {
+				attrs[key] = value
+			}
+		}
+	}
+	return attrs
+}
+
+// splitInfo splits s on whitespace outside quotes.
+func splitInfo(s string) []string {
+	var tokens []string
+	var tok strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			tok.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			tok.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '{' || r == '}':
+			if tok.Len() > 0 {
+				tokens = append(tokens, tok.String())
+				tok.Reset()
+			}
+		default:
+			tok.WriteRune(r)
+		}
+	}
+	if tok.Len() > 0 {
+		tokens = append(tokens, tok.String())
+	}
+	return tokens
+}
+
+// kindFenceHTML is the node kind of a fenced block rendered by a
+// FenceRenderer.
+var kindFenceHTML = ast.NewNodeKind("FenceHTML")
+
+// fenceHTML holds the output of a FenceRenderer in place of the fenced
+// code block it rendered.
+type fenceHTML struct {
+	ast.BaseBlock
+	html string
+}
+
+// Kind implements ast.Node.
+func (n *fenceHTML) Kind() ast.NodeKind { return kindFenceHTML }
+
+// Dump implements ast.Node.
+func (n *fenceHTML) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"HTML": n.html}, nil)
+}
+
+// fenceNodeRenderer writes fenceHTML nodes.
+type fenceNodeRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (fenceNodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindFenceHTML, renderFenceHTML)
+}
+
+func renderFenceHTML(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*fenceHTML).html)
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Foreign content namespaces assigned by the HTML parser.
+const (
+	nsSVG    = "svg"
+	nsMathML = "math"
+)
+
+// dropForeignSubtree lists SVG and MathML elements removed together with
+// their children because they can execute script or embed HTML.
+var dropForeignSubtree = map[string]bool{
+	"script": true, "style": true, "foreignObject": true,
+	"annotation-xml": true,
+}
+
+// allowedSVGTags is the set of SVG elements that survive sanitization.
+// Animation elements are excluded since they can rewrite attributes
+// such as href after sanitization.
+var allowedSVGTags = map[string]bool{
+	"svg": true, "g": true, "defs": true, "title": true, "desc": true,
+	"symbol": true, "use": true, "path": true, "rect": true,
+	"circle": true, "ellipse": true, "line": true, "polyline": true,
+	"polygon": true, "text": true, "tspan": true, "marker": true,
+	"linearGradient": true, "radialGradient": true, "stop": true,
+	"clipPath": true,
+}
+
+// svgAttrs lists attributes kept on allowed SVG elements. The parser
+// reports them with SVG's camel-case spelling (viewBox, refX, ...).
+var svgAttrs = map[string]bool{
+	"id": true, "class": true, "role": true, "aria-label": true,
+	"viewBox": true, "preserveAspectRatio": true, "width": true, "height": true,
+	"x": true, "y": true, "x1": true, "y1": true, "x2": true, "y2": true,
+	"cx": true, "cy": true, "r": true, "rx": true, "ry": true,
+	"dx": true, "dy": true, "d": true, "points": true, "transform": true,
+	"fill": true, "fill-opacity": true, "fill-rule": true, "opacity": true,
+	"stroke": true, "stroke-width": true, "stroke-opacity": true,
+	"stroke-linecap": true, "stroke-linejoin": true,
+	"stroke-dasharray": true, "stroke-dashoffset": true,
+	"font-family": true, "font-size": true, "font-weight": true,
+	"text-anchor": true, "dominant-baseline": true,
+	"offset": true, "stop-color": true, "stop-opacity": true,
+	"gradientUnits": true, "gradientTransform": true,
+	"clip-path": true, "clip-rule": true, "clipPathUnits": true,
+	"marker-start": true, "marker-mid": true, "marker-end": true,
+	"markerWidth": true, "markerHeight": true, "refX": true, "refY": true,
+	"orient": true, "href": true,
+}
+
+// allowedMathMLTags is the set of MathML elements that survive
+// sanitization. maction is excluded; its children are kept.
+var allowedMathMLTags = map[string]bool{
+	"math": true, "mrow": true, "mi": true, "mn": true, "mo": true,
+	"ms": true, "mtext": true, "mspace": true, "msup": true, "msub": true,
+	"msubsup": true, "mfrac": true, "msqrt": true, "mroot": true,
+	"mstyle": true, "merror": true, "mpadded": true, "mphantom": true,
+	"mfenced": true, "menclose": true, "mover": true, "munder": true,
+	"munderover": true, "mtable": true, "mtr": true, "mtd": true,
+	"mlabeledtr": true, "mmultiscripts": true, "mprescripts": true,
+	"none": true, "semantics": true, "annotation": true,
+}
+
+// mathMLAttrs lists attributes kept on allowed MathML elements. href is
+// deliberately absent: MathML allows it on every element.
+var mathMLAttrs = map[string]bool{
+	"id": true, "class": true, "dir": true, "display": true,
+	"mathvariant": true, "mathsize": true, "mathcolor": true,
+	"mathbackground": true, "displaystyle": true, "scriptlevel": true,
+	"fence": true, "separator": true, "separators": true, "stretchy": true,
+	"symmetric": true, "largeop": true, "movablelimits": true,
+	"accent": true, "accentunder": true, "lspace": true, "rspace": true,
+	"linethickness": true, "columnalign": true, "rowalign": true,
+	"columnspan": true, "rowspan": true, "columnlines": true,
+	"rowlines": true, "frame": true, "notation": true, "open": true,
+	"close": true, "width": true, "height": true, "depth": true,
+	"voffset": true, "encoding": true,
+}
+
+// svgURLRefRe matches an attribute value referencing a local fragment,
+// e.g. fill="url(#gradient)".
+var svgURLRefRe = regexp.MustCompile(`^url\(\s*#([^)\s]+)\s*\)$`)
+
+// isForeign reports whether n is an SVG or MathML element.
+func isForeign(n *html.Node) bool {
+	return n.Namespace == nsSVG || n.Namespace == nsMathML
+}
+
+// foreignAllowed reports whether the foreign element n is allowlisted.
+func foreignAllowed(n *html.Node) bool {
+	if n.Namespace == nsSVG {
+		return allowedSVGTags[n.Data]
+	}
+	return allowedMathMLTags[n.Data]
+}
+
+// cleanForeignAttrs filters the attributes of an SVG or MathML element.
+// References (href, xlink:href, url(...)) may only point at fragments
+// inside the document, which are prefixed like any other id.
+func (s *HTMLSanitizer) cleanForeignAttrs(n *html.Node) {
+	allowed := mathMLAttrs
+	if n.Namespace == nsSVG {
+		allowed = svgAttrs
+	}
+
+	kept := make([]html.Attribute, 0, len(n.Attr))
+	for _, attr := range n.Attr {
+		if attr.Namespace == "xlink" && attr.Key == "href" {
+			if frag, ok := strings.CutPrefix(strings.TrimSpace(attr.Val), "#"); ok && frag != "" {
+				attr.Val = "#" + s.PrefixID(frag)
+				kept = append(kept, attr)
+			}
+			continue
+		}
+		if attr.Namespace != "" || strings.HasPrefix(strings.ToLower(attr.Key), "on") {
+			continue
+		}
+
+		switch {
+		case attr.Key == "style":
+			if !s.policy.AllowStyles {
+				continue
+			}
+			if attr.Val = s.cleanStyle(attr.Val); attr.Val == "" {
+				continue
+			}
+		case attr.Key == "id":
+			attr.Val = s.PrefixID(attr.Val)
+		case attr.Key == "href" && allowed["href"]:
+			frag, ok := strings.CutPrefix(strings.TrimSpace(attr.Val), "#")
+			if !ok || frag == "" {
+				continue
+			}
+			attr.Val = "#" + s.PrefixID(frag)
+		case !allowed[attr.Key]:
+			continue
+		default:
+			val := strings.ToLower(attr.Val)
+			if m := svgURLRefRe.FindStringSubmatch(strings.TrimSpace(attr.Val)); m != nil {
+				attr.Val = "url(#" + s.PrefixID(m[1]) + ")"
+			} else if strings.Contains(val, "url(") || strings.Contains(val, "javascript:") || strings.Contains(val, "expression(") {
+				continue
+			}
+		}
+		kept = append(kept, attr)
+	}
+	n.Attr = kept
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// idTransformer applies the id prefix to heading ids and fragment links
+// in the AST, so anchors match the prefixed TOC whether or not the
+// output is sanitized afterwards. Ids in raw HTML are only prefixed by
+// the sanitizer.
+type idTransformer struct {
+	prefix string
+}
+
+func (t idTransformer) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	if t.prefix == "" {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			if id, ok := n.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					n.SetAttributeString("id", []byte(prefixID(t.prefix, string(b))))
+				}
+			}
+		case *ast.Link:
+			if frag, ok := strings.CutPrefix(string(n.Destination), "#"); ok {
+				n.Destination = []byte("#" + prefixID(t.prefix, frag))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// prefixID applies prefix to a user-controlled id. IDs that already
+// carry the prefix are returned unchanged.
+func prefixID(prefix, id string) string {
+	if prefix == "" || id == "" || strings.HasPrefix(id, prefix) {
+		return id
+	}
+	return prefix + id
+}
@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// LimitError reports a document rejected for exceeding a structural
+// limit. Limit is one of the types.Limit* names.
+type LimitError struct {
+	Limit  string
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("document exceeds %s: %d > %d", e.Limit, e.Actual, e.Max)
+}
+
+// checkSourceDepth rejects input whose lines open more nested quotes and
+// list items than max. It runs before parsing because goldmark's cost
+// grows quadratically with container depth. Lines inside top-level
+// fences are skipped.
+func checkSourceDepth(input []byte, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	var fence string
+	for len(input) > 0 {
+		line := input
+		if i := bytes.IndexByte(input, '\n'); i >= 0 {
+			line, input = input[:i], input[i+1:]
+		} else {
+			input = nil
+		}
+
+		if fence != "" {
+			if isFenceClose(string(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if depth := lineDepth(line); depth > max {
+			return &LimitError{Limit: types.LimitDepth, Max: max, Actual: depth}
+		}
+		if m := fenceRe.FindSubmatch(line); m != nil {
+			fence = string(m[1])
+		}
+	}
+	return nil
+}
+
+// lineDepth counts the quote markers and list bullets opening line.
+func lineDepth(line []byte) int {
+	depth := 0
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '>':
+			depth++
+			i++
+		case c == '-' || c == '*' || c == '+':
+			if i+1 < len(line) && (line[i+1] == ' ' || line[i+1] == '\t') {
+				depth++
+				i += 2
+				continue
+			}
+			return depth
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(line) && j-i < 9 && line[j] >= '0' && line[j] <= '9' {
+				j++
+			}
+			if j+1 < len(line) && (line[j] == '.' || line[j] == ')') && (line[j+1] == ' ' || line[j+1] == '\t') {
+				depth++
+				i = j + 2
+				continue
+			}
+			return depth
+		default:
+			return depth
+		}
+	}
+	return depth
+}
+
+// applyLimits enforces l on a parsed document. Exceeding the depth or
+// node limit is an error; excess links, table rows, and code lines are
+// removed from doc and reported as truncations.
+func applyLimits(doc ast.Node, source []byte, l types.Limits) ([]types.Truncation, error) {
+	if l == (types.Limits{}) {
+		return nil, nil
+	}
+
+	var (
+		depth, nodes int
+		links        []ast.Node
+		tables       []*east.Table
+		code         []ast.Node
+		err          error
+	)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		container := n.Kind() == ast.KindBlockquote || n.Kind() == ast.KindListItem
+		if !entering {
+			if container {
+				depth--
+			}
+			return ast.WalkContinue, nil
+		}
+
+		nodes++
+		if l.MaxNodes > 0 && nodes > l.MaxNodes {
+			err = &LimitError{Limit: types.LimitNodes, Max: l.MaxNodes, Actual: nodes}
+			return ast.WalkStop, nil
+		}
+		if container {
+			depth++
+			if l.MaxDepth > 0 && depth > l.MaxDepth {
+				err = &LimitError{Limit: types.LimitDepth, Max: l.MaxDepth, Actual: depth}
+				return ast.WalkStop, nil
+			}
+		}
+
+		switch n := n.(type) {
+		case *ast.Link, *ast.AutoLink:
+			links = append(links, n)
+		case *east.Table:
+			tables = append(tables, n)
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			code = append(code, n)
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var truncs []types.Truncation
+	if l.MaxLinks > 0 && len(links) > l.MaxLinks {
+		for _, n := range links[l.MaxLinks:] {
+			unlink(n, source)
+		}
+		truncs = append(truncs, types.Truncation{Limit: types.LimitLinks, Max: l.MaxLinks, Actual: len(links)})
+	}
+	if l.MaxTableCells > 0 {
+		if cells := truncateTables(tables, l.MaxTableCells); cells > l.MaxTableCells {
+			truncs = append(truncs, types.Truncation{Limit: types.LimitTableCells, Max: l.MaxTableCells, Actual: cells})
+		}
+	}
+	if l.MaxCodeBlockSize > 0 {
+		largest := 0
+		for _, n := range code {
+			largest = max(largest, truncateLines(n, l.MaxCodeBlockSize))
+		}
+		if largest > l.MaxCodeBlockSize {
+			truncs = append(truncs, types.Truncation{Limit: types.LimitCodeBlockSize, Max: l.MaxCodeBlockSize, Actual: largest})
+		}
+	}
+	return truncs, nil
+}
+
+// unlink replaces a link with its text.
+func unlink(n ast.Node, source []byte) {
+	parent := n.Parent()
+	if parent == nil {
+		return
+	}
+	if auto, ok := n.(*ast.AutoLink); ok {
+		parent.ReplaceChild(parent, n, ast.NewString(auto.Label(source)))
+		return
+	}
+	for c := n.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		parent.InsertBefore(parent, n, c)
+		c = next
+	}
+	parent.RemoveChild(parent, n)
+}
+
+// truncateTables keeps the first max cells across tables in document
+// order, dropping whole rows (or whole tables when even the header does
+// not fit). It returns the total cell count before truncation.
+func truncateTables(tables []*east.Table, max int) int {
+	total := 0
+	for _, table := range tables {
+		for row := table.FirstChild(); row != nil; {
+			next := row.NextSibling()
+			total += row.ChildCount()
+			if total > max {
+				if _, header := row.(*east.TableHeader); header {
+					if parent := table.Parent(); parent != nil {
+						parent.RemoveChild(parent, table)
+					}
+				} else {
+					table.RemoveChild(table, row)
+				}
+			}
+			row = next
+		}
+	}
+	return total
+}
+
+// truncateLines cuts a code block to at most max bytes, keeping whole
+// lines. It returns the block's size before truncation.
+func truncateLines(n ast.Node, max int) int {
+	lines := n.Lines()
+	size := 0
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		size += seg.Len()
+	}
+	if size <= max {
+		return size
+	}
+
+	kept := text.NewSegments()
+	used := 0
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		if used+seg.Len() > max {
+			break
+		}
+		used += seg.Len()
+		kept.Append(seg)
+	}
+	n.SetLines(kept)
+	return size
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+// externalRel is the rel value added to hardened external links.
+const externalRel = "nofollow noopener noreferrer"
+
+// hardenLink adds rel and target attributes to an anchor whose href
+// points outside the trusted hosts. Links to trusted hosts, relative
+// links, and fragments are left unchanged.
+func hardenLink(n *html.Node, policy types.LinkPolicy) {
+	href, ok := attrValue(n, "href")
+	if !ok || !isExternalLink(href, policy.TrustedHosts) {
+		return
+	}
+	setAttr(n, "rel", externalRel)
+	if policy.TargetBlank {
+		setAttr(n, "target", "_blank")
+	}
+}
+
+// linkTransformer applies the link policy to markdown links and
+// autolinks in the AST, so they are hardened whether or not the output
+// is sanitized afterwards. Anchors in raw HTML are only hardened by the
+// sanitizer.
+type linkTransformer struct {
+	policy types.LinkPolicy
+}
+
+func (t linkTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	if !t.policy.HardenExternal {
+		return
+	}
+	source := reader.Source()
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var href string
+		switch n := n.(type) {
+		case *ast.Link:
+			href = string(n.Destination)
+		case *ast.AutoLink:
+			href = string(n.URL(source))
+		default:
+			return ast.WalkContinue, nil
+		}
+		if isExternalLink(href, t.policy.TrustedHosts) {
+			n.SetAttributeString("rel", externalRel)
+			if t.policy.TargetBlank {
+				n.SetAttributeString("target", "_blank")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isExternalLink reports whether href is an absolute URL with a host
+// that is not in trusted.
+func isExternalLink(href string, trusted []string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, t := range trusted {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if wildcard, ok := strings.CutPrefix(t, "*."); ok {
+			if host == wildcard || strings.HasSuffix(host, "."+wildcard) {
+				return false
+			}
+			continue
+		}
+		if host == t {
+			return false
+		}
+	}
+	return true
+}
+
+// attrValue returns the value of the named attribute on n.
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// setAttr sets the named attribute on n, replacing any existing value.
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -2,59 +2,204 @@ package parser
 
 import (
 	"bytes"
+	"context"
 	"regexp"
 	"strings"
+	"sync"
 
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/orchestra-mcp/markdown/src/types"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 // MarkdownParser renders markdown to HTML using goldmark.
 type MarkdownParser struct {
-	md   goldmark.Markdown
 	opts types.RenderOptions
+	reg  *Registry
+
+	mu      sync.Mutex
+	md      goldmark.Markdown
+	fences  map[string]FenceRenderer
+	version uint64
 }
 
 // New creates a MarkdownParser with the given options.
 func New(opts types.RenderOptions) *MarkdownParser {
+	return NewWithRegistry(opts, nil)
+}
+
+// NewWithRegistry creates a MarkdownParser that also applies the
+// extensions in reg. Changes to reg take effect on the next render.
+func NewWithRegistry(opts types.RenderOptions, reg *Registry) *MarkdownParser {
+	ext := reg.snapshot()
+	return &MarkdownParser{
+		opts:    opts,
+		reg:     reg,
+		md:      build(opts, ext),
+		fences:  fenceSet(opts, ext.fences),
+		version: ext.version,
+	}
+}
+
+// build assembles the goldmark instance for opts and the registered
+// extensions.
+func build(opts types.RenderOptions, ext registrySnapshot) goldmark.Markdown {
 	theme := opts.CodeTheme
 	if theme == "" {
 		theme = "monokai"
 	}
 
-	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM,
-			extension.Typographer,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle(theme),
+	// Unknown raw HTML modes fall back to escaping, never to emitting.
+	rendererOpts := []renderer.Option{html.WithUnsafe()}
+	if opts.RawHTML != "" && opts.RawHTML != types.RawHTMLAllow {
+		rendererOpts = []renderer.Option{
+			renderer.WithNodeRenderers(
+				util.Prioritized(&rawHTMLRenderer{mode: opts.RawHTML}, 100),
 			),
-		),
+		}
+	}
+
+	highlight := []highlighting.Option{highlighting.WithStyle(theme)}
+	if opts.CodeClasses {
+		highlight = append(highlight, highlighting.WithFormatOptions(chromahtml.WithClasses(true)))
+	}
+
+	rendererOpts = append(rendererOpts, renderer.WithNodeRenderers(
+		util.Prioritized(fenceNodeRenderer{}, 100),
+		util.Prioritized(checkedRenderer{highlighting.NewHTMLRenderer(highlight...)}, 200),
+	))
+
+	extensions := []goldmark.Extender{
+		extension.GFM,
+		extension.Typographer,
+	}
+
+	return goldmark.New(
+		goldmark.WithExtensions(append(extensions, ext.extensions...)...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
+			parser.WithASTTransformers(ext.transformers...),
+			parser.WithASTTransformers(
+				util.Prioritized(linkTransformer{policy: opts.Sanitize.Links}, 10000),
+				util.Prioritized(idTransformer{prefix: opts.Sanitize.IDPrefix}, 10000),
+			),
 		),
-		goldmark.WithRendererOptions(
-			html.WithUnsafe(),
-		),
+		goldmark.WithRendererOptions(rendererOpts...),
 	)
+}
 
-	return &MarkdownParser{md: md, opts: opts}
+// contextKey is the document meta key holding the context of the
+// render in progress.
+const contextKey = "orchestra.markdown.context"
+
+// renderContext returns the context stored on n's document, or the
+// background context when there is none.
+func renderContext(n ast.Node) context.Context {
+	if doc := n.OwnerDocument(); doc != nil {
+		if ctx, ok := doc.Meta()[contextKey].(context.Context); ok {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// checkedRenderer is a node renderer whose functions stop the render
+// once its context is done. It wraps the highlighter, so a top-level
+// block holding many code blocks is not highlighted to the end after
+// cancellation.
+type checkedRenderer struct {
+	renderer.NodeRenderer
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r checkedRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	r.NodeRenderer.RegisterFuncs(checkedRegisterer{reg})
+}
+
+// SetOption implements renderer.SetOptioner, passing renderer options
+// on to the wrapped renderer.
+func (r checkedRenderer) SetOption(name renderer.OptionName, value any) {
+	if s, ok := r.NodeRenderer.(renderer.SetOptioner); ok {
+		s.SetOption(name, value)
+	}
+}
+
+// checkedRegisterer checks the render's context before each node the
+// functions registered through it render.
+type checkedRegisterer struct {
+	renderer.NodeRendererFuncRegisterer
+}
+
+func (r checkedRegisterer) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	r.NodeRendererFuncRegisterer.Register(kind, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			if err := renderContext(n).Err(); err != nil {
+				return ast.WalkStop, err
+			}
+		}
+		return fn(w, source, n, entering)
+	})
+}
+
+// markdown returns the goldmark instance and fence renderers for the
+// current registry version, rebuilding them after the registry changed.
+func (p *MarkdownParser) markdown() (goldmark.Markdown, map[string]FenceRenderer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reg != nil && p.version != p.reg.Version() {
+		ext := p.reg.snapshot()
+		p.md = build(p.opts, ext)
+		p.fences = fenceSet(p.opts, ext.fences)
+		p.version = ext.version
+	}
+	return p.md, p.fences
+}
+
+// Registry returns the extension registry the parser applies, or nil.
+func (p *MarkdownParser) Registry() *Registry {
+	return p.reg
+}
+
+// Options returns the options the parser was created with.
+func (p *MarkdownParser) Options() types.RenderOptions {
+	return p.opts
 }
 
 // Render converts markdown bytes to an HTML string.
 func (p *MarkdownParser) Render(input []byte) (*types.RenderResult, error) {
-	var buf bytes.Buffer
-	if err := p.md.Convert(input, &buf); err != nil {
+	return p.RenderContext(context.Background(), input)
+}
+
+// RenderContext is Render with cancellation. The document is rendered
+// one top-level block at a time so highlighting large documents stops
+// soon after ctx is done.
+func (p *MarkdownParser) RenderContext(ctx context.Context, input []byte) (*types.RenderResult, error) {
+	blocks, err := p.RenderBlocks(ctx, input)
+	if err != nil {
 		return nil, err
 	}
 
+	result := p.Extract(input)
+	result.HTML = strings.Join(blocks.HTML, "")
+	result.Truncations = blocks.Truncations
+	result.Warnings = MergeDiagnostics(result.Warnings, blocks.Warnings)
+	return result, nil
+}
+
+// Extract returns a result holding everything a render produces except
+// the HTML: code blocks, the TOC when enabled, frontmatter, and warnings.
+func (p *MarkdownParser) Extract(input []byte) *types.RenderResult {
 	result := &types.RenderResult{
-		HTML:       buf.String(),
 		CodeBlocks: p.ExtractCodeBlocks(input),
+		Warnings:   p.Diagnose(input),
 	}
 
 	if p.opts.EnableTOC {
@@ -66,7 +211,60 @@ func (p *MarkdownParser) Render(input []byte) (*types.RenderResult, error) {
 		result.Metadata = meta
 	}
 
-	return result, nil
+	return result
+}
+
+// Blocks is a document rendered one top-level block at a time.
+type Blocks struct {
+	HTML []string
+	// Truncations lists the limits that cut the output short.
+	Truncations []types.Truncation
+	// Warnings reports fence renderers that failed, in source order.
+	Warnings []types.Diagnostic
+}
+
+// RenderBlocks renders each top-level block of the document to its own
+// HTML fragment, checking ctx between blocks and before each highlighted
+// code block. The parser's limits are
+// enforced first: a *LimitError rejects the document, and anything cut
+// short is reported in the truncations. Fenced blocks with a renderer
+// are rendered next, before the document is written out.
+func (p *MarkdownParser) RenderBlocks(ctx context.Context, input []byte) (*Blocks, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkSourceDepth(input, p.opts.Limits.MaxDepth); err != nil {
+		return nil, err
+	}
+	source := p.maskFrontmatter(input)
+	md, fences := p.markdown()
+	doc := md.Parser().Parse(text.NewReader(source))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc.OwnerDocument().AddMeta(contextKey, ctx)
+	truncs, err := applyLimits(doc, source, p.opts.Limits)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := renderFences(ctx, doc, source, fences)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Blocks{Truncations: truncs, Warnings: warnings}
+	var buf bytes.Buffer
+	for block := doc.FirstChild(); block != nil; block = block.NextSibling() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		buf.Reset()
+		if err := md.Renderer().Render(&buf, source, block); err != nil {
+			return nil, err
+		}
+		out.HTML = append(out.HTML, buf.String())
+	}
+	return out, nil
 }
 
 // headingRe matches ATX-style headings (# Heading).
@@ -74,7 +272,17 @@ var headingRe = regexp.MustCompile(`(?m)^(#{1,6})\s+(.+)$`)
 
 // ExtractTOC extracts ATX-style headings from markdown source.
 func (p *MarkdownParser) ExtractTOC(input []byte) []types.TOCEntry {
-	matches := headingRe.FindAllSubmatch(input, -1)
+	entries, _ := p.ExtractTOCContext(context.Background(), input)
+	return entries
+}
+
+// ExtractTOCContext is ExtractTOC with cancellation, checked between
+// headings.
+func (p *MarkdownParser) ExtractTOCContext(ctx context.Context, input []byte) ([]types.TOCEntry, error) {
+	matches, err := findAllContext(ctx, headingRe, input)
+	if err != nil {
+		return nil, err
+	}
 	entries := make([]types.TOCEntry, 0, len(matches))
 
 	for _, m := range matches {
@@ -87,7 +295,7 @@ func (p *MarkdownParser) ExtractTOC(input []byte) []types.TOCEntry {
 		})
 	}
 
-	return entries
+	return entries, nil
 }
 
 // codeBlockRe matches fenced code blocks with optional language.
@@ -95,7 +303,17 @@ var codeBlockRe = regexp.MustCompile("(?ms)^```(\\w*)\\n(.*?)^```")
 
 // ExtractCodeBlocks extracts fenced code blocks from markdown source.
 func (p *MarkdownParser) ExtractCodeBlocks(input []byte) []types.CodeBlock {
-	matches := codeBlockRe.FindAllSubmatch(input, -1)
+	blocks, _ := p.ExtractCodeBlocksContext(context.Background(), input)
+	return blocks
+}
+
+// ExtractCodeBlocksContext is ExtractCodeBlocks with cancellation,
+// checked between blocks.
+func (p *MarkdownParser) ExtractCodeBlocksContext(ctx context.Context, input []byte) ([]types.CodeBlock, error) {
+	matches, err := findAllContext(ctx, codeBlockRe, input)
+	if err != nil {
+		return nil, err
+	}
 	blocks := make([]types.CodeBlock, 0, len(matches))
 
 	for _, m := range matches {
@@ -107,7 +325,38 @@ func (p *MarkdownParser) ExtractCodeBlocks(input []byte) []types.CodeBlock {
 		})
 	}
 
-	return blocks
+	return blocks, nil
+}
+
+// findAllContext is FindAllSubmatch for the line-anchored patterns
+// above, checking ctx before each search. A search resumes at the line
+// after the previous match, the first place the next match can start.
+func findAllContext(ctx context.Context, re *regexp.Regexp, input []byte) ([][][]byte, error) {
+	var matches [][][]byte
+	for pos := 0; pos < len(input); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		loc := re.FindSubmatchIndex(input[pos:])
+		if loc == nil {
+			break
+		}
+		m := make([][]byte, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = input[pos+loc[2*i] : pos+loc[2*i+1]]
+			}
+		}
+		matches = append(matches, m)
+
+		end := pos + loc[1]
+		nl := bytes.IndexByte(input[end:], '\n')
+		if nl < 0 {
+			break
+		}
+		pos = end + nl + 1
+	}
+	return matches, nil
 }
 
 // ExtractFrontmatter extracts YAML frontmatter delimited by --- lines.
@@ -141,6 +390,24 @@ func (p *MarkdownParser) ExtractFrontmatter(input []byte) (map[string]string, []
 	return meta, body
 }
 
+// maskFrontmatter returns input with its frontmatter blanked out: every
+// byte but newlines becomes a space, so the block parses as blank lines
+// while source offsets stay those of input.
+func (p *MarkdownParser) maskFrontmatter(input []byte) []byte {
+	_, body := p.ExtractFrontmatter(input)
+	n := len(input) - len(body)
+	if n == 0 {
+		return input
+	}
+	out := bytes.Clone(input)
+	for i := range n {
+		if out[i] != '\n' {
+			out[i] = ' '
+		}
+	}
+	return out
+}
+
 // slugify converts a heading text to a URL-safe ID.
 func slugify(s string) string {
 	s = strings.ToLower(s)
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fenceRe matches a fenced code block delimiter line.
+var fenceRe = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// delimRowRe matches a complete or partially typed table delimiter row.
+var delimRowRe = regexp.MustCompile(`^\s*\|?\s*:?-*:?\s*(\|\s*:?-*:?\s*)*\|?\s*$`)
+
+// SplitStable splits streamed markdown into blocks that can no longer
+// change and the trailing block that may still grow. A block becomes
+// stable once it is followed by a blank line and the first line of an
+// unindented next block; blank lines inside fenced code never split.
+func SplitStable(src string) (stable []string, tail string) {
+	var (
+		blockStart int
+		pending    = -1 // start of the blank line run ending the block
+		fence      string
+		lineStart  int
+	)
+	for lineStart < len(src) {
+		end := strings.IndexByte(src[lineStart:], '\n')
+		complete := end >= 0
+		if !complete {
+			end = len(src) - lineStart
+		}
+		line := src[lineStart : lineStart+end]
+		next := lineStart + end + 1
+
+		switch {
+		case fence != "":
+			if complete && isFenceClose(line, fence) {
+				fence = ""
+			}
+		case strings.TrimSpace(line) == "":
+			if complete && pending < 0 {
+				pending = lineStart
+			}
+		default:
+			if pending >= 0 && line[0] != ' ' && line[0] != '\t' {
+				stable = append(stable, src[blockStart:pending])
+				blockStart = lineStart
+			}
+			pending = -1
+			if m := fenceRe.FindStringSubmatch(line); m != nil && complete {
+				fence = m[1]
+			}
+		}
+		lineStart = next
+	}
+	return stable, src[blockStart:]
+}
+
+// isFenceClose reports whether line closes a fence opened with open.
+func isFenceClose(line, open string) bool {
+	m := fenceRe.FindStringSubmatch(line)
+	return m != nil && m[1][0] == open[0] && len(m[1]) >= len(open) &&
+		strings.TrimSpace(line[len(m[0]):]) == ""
+}
+
+// RepairPartial closes constructs left open in a partially streamed
+// block so it renders the way it will once complete: unterminated
+// fences, a table whose delimiter row is missing or half typed, and
+// dangling emphasis, strikethrough, code span, or link markers.
+func RepairPartial(block string) string {
+	if fence := openFence(block); fence != "" {
+		if !strings.HasSuffix(block, "\n") {
+			block += "\n"
+		}
+		return block + fence + "\n"
+	}
+	return repairInline(repairTable(block))
+}
+
+// openFence returns the delimiter of a fence left open at the end of
+// block, or "" if all fences are closed.
+func openFence(block string) string {
+	var fence string
+	for _, line := range strings.Split(block, "\n") {
+		if fence == "" {
+			if m := fenceRe.FindStringSubmatch(line); m != nil {
+				fence = m[1]
+			}
+		} else if isFenceClose(line, fence) {
+			fence = ""
+		}
+	}
+	return fence
+}
+
+// repairTable completes the delimiter row of a table whose header has
+// been streamed but whose delimiter row is missing or incomplete.
+func repairTable(block string) string {
+	lines := strings.Split(strings.TrimRight(block, "\n"), "\n")
+	header := strings.TrimSpace(lines[0])
+	if !strings.HasPrefix(header, "|") || len(lines) > 2 {
+		return block
+	}
+	if len(lines) == 2 && !delimRowRe.MatchString(lines[1]) {
+		return block
+	}
+
+	cols := strings.Count(strings.Trim(header, "|"), "|") + 1
+	delim := "|" + strings.Repeat("---|", cols)
+	return lines[0] + "\n" + delim + "\n"
+}
+
+// repairInline closes inline markers left open at the end of block.
+// A marker with nothing after it is dropped instead of closed.
+func repairInline(block string) string {
+	type open struct {
+		marker string
+		pos    int
+	}
+	var stack []open
+	toggle := func(marker string, pos int) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].marker == marker {
+				stack = append(stack[:i], stack[i+1:]...)
+				return
+			}
+		}
+		stack = append(stack, open{marker, pos})
+	}
+	popTo := func(marker string) {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].marker == marker {
+				stack = stack[:i]
+				return
+			}
+		}
+	}
+
+	code := open{}
+	for i := 0; i < len(block); {
+		c := block[i]
+		run := 1
+		for i+run < len(block) && block[i+run] == c {
+			run++
+		}
+
+		switch {
+		case code.marker != "":
+			if c == '`' && run == len(code.marker) {
+				code = open{}
+			}
+		case c == '\\':
+			run = 2
+		case c == '`':
+			code = open{block[i : i+run], i}
+		case c == '~' && run == 2:
+			toggle("~~", i)
+		case c == '*':
+			if run == 1 && atLineStart(block, i) && i+1 < len(block) && block[i+1] == ' ' {
+				break // list bullet
+			}
+			if i+run < len(block) && isSpaceBefore(block, i) && isSpace(block[i+run]) {
+				break // surrounded by spaces, cannot delimit emphasis
+			}
+			for n := run; n > 0; {
+				if n >= 2 {
+					toggle("**", i+run-n)
+					n -= 2
+				} else {
+					toggle("*", i+run-n)
+					n--
+				}
+			}
+		case c == '[':
+			stack = append(stack, open{"[", i})
+		case c == ']':
+			popTo("[")
+			if i+1 < len(block) && block[i+1] == '(' {
+				stack = append(stack, open{"(", i + 1})
+				run = 2
+			}
+		case c == ')':
+			popTo("(")
+		}
+		i = min(i+run, len(block))
+	}
+	if code.marker != "" {
+		stack = append(stack, code)
+	}
+
+	// Drop markers that trail the block with nothing after them, then
+	// close the rest innermost first.
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.marker == "[" || strings.TrimSpace(block[top.pos+len(top.marker):]) != "" {
+			break
+		}
+		block = block[:top.pos]
+		stack = stack[:len(stack)-1]
+	}
+	var closers strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch m := stack[i].marker; m {
+		case "[":
+		case "(":
+			closers.WriteString(")")
+		default:
+			closers.WriteString(m)
+		}
+	}
+	return strings.TrimRight(block, " \t\n") + closers.String() + "\n"
+}
+
+// atLineStart reports whether position i is preceded only by
+// indentation on its line.
+func atLineStart(s string, i int) bool {
+	j := strings.LastIndexByte(s[:i], '\n') + 1
+	return strings.TrimLeft(s[j:i], " \t") == ""
+}
+
+// isSpaceBefore reports whether position i is at the start of s or
+// follows whitespace.
+func isSpaceBefore(s string, i int) bool {
+	return i == 0 || isSpace(s[i-1])
+}
+
+// isSpace reports whether c is ASCII whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+// Registry holds markdown syntax contributed by other plugins: goldmark
+// extensions, fence renderers keyed by language, and AST transformers.
+// Parsers created with NewWithRegistry pick up registrations on their
+// next render.
+//
+// Priorities follow goldmark: lower values take precedence. Extensions
+// and transformers run in ascending priority order, and when several
+// renderers claim a fence language the lowest priority wins.
+type Registry struct {
+	mu           sync.RWMutex
+	version      uint64
+	extensions   []registration[goldmark.Extender]
+	fences       []registration[FenceRenderer]
+	transformers []registration[parser.ASTTransformer]
+}
+
+// registration is one named entry in the registry. For fences the name
+// is the language.
+type registration[T any] struct {
+	name     string
+	priority int
+	value    T
+}
+
+// NewRegistry creates an empty extension registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterExtension adds a goldmark extension under name, replacing any
+// extension already registered with that name.
+func (r *Registry) RegisterExtension(name string, ext goldmark.Extender, priority int) error {
+	if name == "" || ext == nil {
+		return errors.New("extension name and value are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extensions = upsert(r.extensions, registration[goldmark.Extender]{name, priority, ext})
+	r.version++
+	return nil
+}
+
+// RegisterFence renders fenced code blocks whose language is lang with
+// fn instead of the syntax highlighter. Registering the same language
+// twice at the same priority replaces the earlier renderer.
+func (r *Registry) RegisterFence(lang string, fn FenceRenderer, priority int) error {
+	if lang == "" || fn == nil {
+		return errors.New("fence language and renderer are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.fences, func(f registration[FenceRenderer]) bool {
+		return f.name == lang && f.priority == priority
+	})
+	if i >= 0 {
+		r.fences[i].value = fn
+	} else {
+		r.fences = append(r.fences, registration[FenceRenderer]{lang, priority, fn})
+	}
+	r.version++
+	return nil
+}
+
+// RegisterTransformer adds an AST transformer under name, replacing any
+// transformer already registered with that name.
+func (r *Registry) RegisterTransformer(name string, t parser.ASTTransformer, priority int) error {
+	if name == "" || t == nil {
+		return errors.New("transformer name and value are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transformers = upsert(r.transformers, registration[parser.ASTTransformer]{name, priority, t})
+	r.version++
+	return nil
+}
+
+// Unregister removes the extensions and transformers registered under
+// name and the fence renderers for the language name. It reports
+// whether anything was removed.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.extensions) + len(r.fences) + len(r.transformers)
+	r.extensions = slices.DeleteFunc(r.extensions, func(e registration[goldmark.Extender]) bool { return e.name == name })
+	r.fences = slices.DeleteFunc(r.fences, func(f registration[FenceRenderer]) bool { return f.name == name })
+	r.transformers = slices.DeleteFunc(r.transformers, func(t registration[parser.ASTTransformer]) bool { return t.name == name })
+	if n == len(r.extensions)+len(r.fences)+len(r.transformers) {
+		return false
+	}
+	r.version++
+	return true
+}
+
+// Version increases with every change to the registry. A nil registry
+// has version 0.
+func (r *Registry) Version() uint64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Languages returns the fence languages with a registered renderer,
+// sorted.
+func (r *Registry) Languages() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	langs := make([]string, 0, len(r.fences))
+	for _, f := range r.fences {
+		langs = append(langs, f.name)
+	}
+	slices.Sort(langs)
+	return slices.Compact(langs)
+}
+
+// registrySnapshot is the registry contents one goldmark instance is
+// built from.
+type registrySnapshot struct {
+	version      uint64
+	extensions   []goldmark.Extender
+	fences       map[string]FenceRenderer
+	transformers []util.PrioritizedValue
+}
+
+func (r *Registry) snapshot() registrySnapshot {
+	if r == nil {
+		return registrySnapshot{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := registrySnapshot{version: r.version}
+	for _, e := range sortedByPriority(r.extensions) {
+		s.extensions = append(s.extensions, e.value)
+	}
+	// Higher-precedence renderers come last so they overwrite the rest.
+	fences := sortedByPriority(r.fences)
+	if len(fences) > 0 {
+		s.fences = make(map[string]FenceRenderer, len(fences))
+		for _, f := range slices.Backward(fences) {
+			s.fences[f.name] = f.value
+		}
+	}
+	for _, t := range r.transformers {
+		s.transformers = append(s.transformers, util.Prioritized(t.value, t.priority))
+	}
+	return s
+}
+
+// upsert replaces the entry named like reg or appends it.
+func upsert[T any](list []registration[T], reg registration[T]) []registration[T] {
+	i := slices.IndexFunc(list, func(e registration[T]) bool { return e.name == reg.name })
+	if i >= 0 {
+		list[i] = reg
+		return list
+	}
+	return append(list, reg)
+}
+
+// sortedByPriority returns a copy of list in ascending priority order,
+// keeping registration order between equal priorities.
+func sortedByPriority[T any](list []registration[T]) []registration[T] {
+	sorted := slices.Clone(list)
+	slices.SortStableFunc(sorted, func(a, b registration[T]) int { return a.priority - b.priority })
+	return sorted
+}
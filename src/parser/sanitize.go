@@ -2,8 +2,10 @@ package parser
 
 import (
 	"bytes"
+	"context"
 	"strings"
 
+	"github.com/orchestra-mcp/markdown/src/types"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -11,11 +13,26 @@ import (
 // HTMLSanitizer strips dangerous HTML elements and attributes using a
 // DOM-based allowlist. Parses HTML into a node tree, walks every node,
 // and removes anything not explicitly allowed.
-type HTMLSanitizer struct{}
+type HTMLSanitizer struct {
+	policy     types.SanitizePolicy
+	styleProps map[string]bool
+}
 
-// NewSanitizer creates a new HTMLSanitizer.
+// NewSanitizer creates a new HTMLSanitizer with the default policy.
 func NewSanitizer() *HTMLSanitizer {
-	return &HTMLSanitizer{}
+	return NewSanitizerWithPolicy(types.SanitizePolicy{})
+}
+
+// NewSanitizerWithPolicy creates an HTMLSanitizer using the given policy.
+func NewSanitizerWithPolicy(policy types.SanitizePolicy) *HTMLSanitizer {
+	s := &HTMLSanitizer{policy: policy, styleProps: defaultStyleProps}
+	if len(policy.StyleProperties) > 0 {
+		s.styleProps = make(map[string]bool, len(policy.StyleProperties))
+		for _, prop := range policy.StyleProperties {
+			s.styleProps[strings.ToLower(strings.TrimSpace(prop))] = true
+		}
+	}
+	return s
 }
 
 // dropEntireSubtree lists tags whose entire subtree (including text
@@ -56,37 +73,95 @@ var safeAttrs = map[string]bool{
 // Sanitize parses HTML into a DOM, removes disallowed elements and
 // attributes, and renders the cleaned tree back to a string.
 func (s *HTMLSanitizer) Sanitize(raw string) string {
+	out, _ := s.SanitizeContext(context.Background(), raw)
+	return out
+}
+
+// SanitizeContext is Sanitize with cancellation: the walk checks ctx
+// periodically and returns its error once it is done. Input nested past
+// the policy's MaxDepth fails with a *LimitError.
+func (s *HTMLSanitizer) SanitizeContext(ctx context.Context, raw string) (string, error) {
 	nodes, err := html.ParseFragment(
 		strings.NewReader(raw),
 		&html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"},
 	)
 	if err != nil {
-		return html.EscapeString(raw)
+		return html.EscapeString(raw), nil
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
 
+	w := &walker{ctx: ctx, maxDepth: s.policy.MaxDepth}
 	var buf bytes.Buffer
 	for _, n := range nodes {
-		renderClean(&buf, n)
+		s.renderClean(&buf, n, w)
+		if w.err != nil {
+			return "", w.err
+		}
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// walker carries cancellation and depth state through a sanitizer walk.
+type walker struct {
+	ctx      context.Context
+	visited  int
+	depth    int
+	maxDepth int
+	err      error
+}
+
+// cancelled counts a visited node and reports whether the walk must stop.
+// The context is only polled every 256 nodes to keep the walk cheap.
+func (w *walker) cancelled() bool {
+	if w.err != nil {
+		return true
+	}
+	w.visited++
+	if w.visited%256 == 0 {
+		w.err = w.ctx.Err()
+	}
+	return w.err != nil
+}
+
+// enter descends one element, failing the walk with a *LimitError once
+// the policy's depth cap is passed.
+func (w *walker) enter() bool {
+	w.depth++
+	if w.maxDepth > 0 && w.depth > w.maxDepth {
+		w.err = &LimitError{Limit: types.LimitDepth, Max: w.maxDepth, Actual: w.depth}
+		return false
 	}
-	return strings.TrimSpace(buf.String())
+	return true
 }
 
 // renderClean handles a single top-level node: drops dangerous elements,
 // unwraps disallowed-but-safe elements, and cleans allowed elements.
-func renderClean(buf *bytes.Buffer, n *html.Node) {
+func (s *HTMLSanitizer) renderClean(buf *bytes.Buffer, n *html.Node, w *walker) {
+	if w.cancelled() {
+		return
+	}
 	switch n.Type {
 	case html.ElementNode:
-		if dropEntireSubtree[n.DataAtom] {
+		if dropElement(n) {
 			return
 		}
-		if !allowedTags[n.DataAtom] {
+		if !allowElement(n) {
+			if !w.enter() {
+				return
+			}
 			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				renderClean(buf, c)
+				s.renderClean(buf, c, w)
 			}
+			w.depth--
+			return
+		}
+		s.cleanAttrs(n)
+		s.walkAndClean(n, w)
+		if w.err != nil {
 			return
 		}
-		cleanAttrs(n)
-		walkAndClean(n)
 		if err := html.Render(buf, n); err != nil {
 			return
 		}
@@ -100,23 +175,34 @@ func renderClean(buf *bytes.Buffer, n *html.Node) {
 }
 
 // walkAndClean recursively removes disallowed nodes and attributes.
-func walkAndClean(n *html.Node) {
+func (s *HTMLSanitizer) walkAndClean(n *html.Node, w *walker) {
 	var next *html.Node
 	for c := n.FirstChild; c != nil; c = next {
+		if w.cancelled() {
+			return
+		}
 		next = c.NextSibling
 
 		switch c.Type {
 		case html.ElementNode:
-			if dropEntireSubtree[c.DataAtom] {
+			if dropElement(c) {
 				n.RemoveChild(c)
 				continue
 			}
-			if !allowedTags[c.DataAtom] {
-				promoteChildren(n, c)
+			if !allowElement(c) {
+				// Visit the promoted children next; they still
+				// need cleaning.
+				if first := promoteChildren(n, c); first != nil {
+					next = first
+				}
 				continue
 			}
-			cleanAttrs(c)
-			walkAndClean(c)
+			s.cleanAttrs(c)
+			if !w.enter() {
+				return
+			}
+			s.walkAndClean(c, w)
+			w.depth--
 		case html.TextNode:
 			// keep
 		default:
@@ -125,25 +211,71 @@ func walkAndClean(n *html.Node) {
 	}
 }
 
+// PrefixID applies the policy's IDPrefix to a user-controlled id. IDs
+// that already carry the prefix are returned unchanged.
+func (s *HTMLSanitizer) PrefixID(id string) string {
+	return prefixID(s.policy.IDPrefix, id)
+}
+
+// dropElement reports whether n must be removed with its subtree.
+func dropElement(n *html.Node) bool {
+	if isForeign(n) {
+		return dropForeignSubtree[n.Data]
+	}
+	return dropEntireSubtree[n.DataAtom]
+}
+
+// allowElement reports whether n survives sanitization.
+func allowElement(n *html.Node) bool {
+	if isForeign(n) {
+		return foreignAllowed(n)
+	}
+	return allowedTags[n.DataAtom]
+}
+
 // promoteChildren moves all children of child into parent (before
-// child's position) and removes child.
-func promoteChildren(parent, child *html.Node) {
+// child's position), removes child, and returns the first moved node.
+func promoteChildren(parent, child *html.Node) *html.Node {
+	first := child.FirstChild
 	for child.FirstChild != nil {
 		moved := child.FirstChild
 		child.RemoveChild(moved)
 		parent.InsertBefore(moved, child)
 	}
 	parent.RemoveChild(child)
+	return first
 }
 
 // cleanAttrs removes dangerous attributes from an element node.
-func cleanAttrs(n *html.Node) {
+func (s *HTMLSanitizer) cleanAttrs(n *html.Node) {
+	if isForeign(n) {
+		s.cleanForeignAttrs(n)
+		return
+	}
+
 	kept := make([]html.Attribute, 0, len(n.Attr))
 	for _, attr := range n.Attr {
 		key := strings.ToLower(attr.Key)
 		if strings.HasPrefix(key, "on") {
 			continue
 		}
+		if key == "id" || key == "name" {
+			if key == "name" && s.policy.IDPrefix == "" {
+				continue
+			}
+			attr.Val = s.PrefixID(attr.Val)
+			kept = append(kept, attr)
+			continue
+		}
+		if key == "style" {
+			if !s.policy.AllowStyles {
+				continue
+			}
+			if attr.Val = s.cleanStyle(attr.Val); attr.Val != "" {
+				kept = append(kept, attr)
+			}
+			continue
+		}
 		if !safeAttrs[key] {
 			continue
 		}
@@ -152,8 +284,15 @@ func cleanAttrs(n *html.Node) {
 			if strings.HasPrefix(val, "javascript:") || strings.HasPrefix(val, "data:") {
 				continue
 			}
+			if frag, ok := strings.CutPrefix(attr.Val, "#"); ok && key == "href" {
+				attr.Val = "#" + s.PrefixID(frag)
+			}
 		}
 		kept = append(kept, attr)
 	}
 	n.Attr = kept
+
+	if n.DataAtom == atom.A && s.policy.Links.HardenExternal {
+		hardenLink(n, s.policy.Links)
+	}
 }
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultStyleProps lists CSS properties kept when inline styles are
+// allowed. Layout properties that can move content out of its box
+// (position, top, z-index, ...) are deliberately absent.
+var defaultStyleProps = map[string]bool{
+	"color": true, "background-color": true,
+	"font-weight": true, "font-style": true, "font-size": true,
+	"text-align": true, "text-decoration": true, "vertical-align": true,
+	"white-space": true, "width": true, "height": true,
+	"min-width": true, "max-width": true, "border-collapse": true,
+}
+
+// styleValueRe matches the characters a safe declaration value may use.
+var styleValueRe = regexp.MustCompile(`^[a-zA-Z0-9#%.,\s()!+-]*$`)
+
+// styleFuncRe matches CSS function calls such as rgb( or url(.
+var styleFuncRe = regexp.MustCompile(`([a-zA-Z-]*)\s*\(`)
+
+// safeStyleFuncs lists the only CSS functions allowed in values.
+var safeStyleFuncs = map[string]bool{
+	"rgb": true, "rgba": true, "hsl": true, "hsla": true,
+}
+
+// cleanStyle parses an inline style attribute and returns only the
+// declarations with allowed properties and safe values.
+func (s *HTMLSanitizer) cleanStyle(style string) string {
+	var kept []string
+	for _, decl := range strings.Split(style, ";") {
+		prop, val, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		val = strings.TrimSpace(val)
+		if prop == "" || val == "" || !s.styleProps[prop] || !safeStyleValue(val) {
+			continue
+		}
+		kept = append(kept, prop+": "+val)
+	}
+	return strings.Join(kept, "; ")
+}
+
+// safeStyleValue rejects values containing escapes, comments, or any
+// function other than the color functions (url(), expression(), ...).
+func safeStyleValue(val string) bool {
+	if !styleValueRe.MatchString(val) {
+		return false
+	}
+	for _, m := range styleFuncRe.FindAllStringSubmatch(val, -1) {
+		if !safeStyleFuncs[strings.ToLower(m[1])] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// RenderText renders the document as plain text: markup is removed,
+// blocks are separated by blank lines, list items keep their markers,
+// table cells are tab-separated, and code is kept verbatim. Raw HTML is
+// dropped. Frontmatter is not part of the text. The parser's limits
+// apply as in RenderBlocks.
+func (p *MarkdownParser) RenderText(ctx context.Context, input []byte) (string, []types.Truncation, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	if err := checkSourceDepth(input, p.opts.Limits.MaxDepth); err != nil {
+		return "", nil, err
+	}
+	_, body := p.ExtractFrontmatter(input)
+	md, _ := p.markdown()
+	doc := md.Parser().Parse(text.NewReader(body))
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	truncs, err := applyLimits(doc, body, p.opts.Limits)
+	if err != nil {
+		return "", nil, err
+	}
+
+	w := &textWriter{source: body}
+	var out strings.Builder
+	for block := doc.FirstChild(); block != nil; block = block.NextSibling() {
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+		lines := w.lines(block)
+		if len(lines) == 0 {
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return out.String(), truncs, nil
+}
+
+// textWriter renders the blocks of one document as plain text lines.
+type textWriter struct {
+	source []byte
+}
+
+// lines returns the text of block n, one entry per line.
+func (w *textWriter) lines(n ast.Node) []string {
+	switch n := n.(type) {
+	case *ast.HTMLBlock, *ast.ThematicBreak:
+		return nil
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		var out []string
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			out = append(out, strings.TrimRight(string(line.Value(w.source)), "\r\n"))
+		}
+		return out
+	case *ast.List:
+		return w.list(n)
+	case *east.Table:
+		var out []string
+		for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+			cells := make([]string, 0, row.ChildCount())
+			for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+				cells = append(cells, w.inline(cell))
+			}
+			out = append(out, strings.Join(cells, "\t"))
+		}
+		return out
+	case *ast.Paragraph, *ast.Heading, *ast.TextBlock:
+		return strings.Split(w.inline(n), "\n")
+	}
+	return w.children(n, true)
+}
+
+// children returns the lines of n's child blocks, with a blank line
+// between blocks when spaced is set.
+func (w *textWriter) children(n ast.Node, spaced bool) []string {
+	var out []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		lines := w.lines(c)
+		if len(lines) == 0 {
+			continue
+		}
+		if spaced && len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+	return out
+}
+
+// list returns the items of n with their markers, continuation lines
+// indented under the marker. Loose lists keep blank lines between items.
+func (w *textWriter) list(n *ast.List) []string {
+	var out []string
+	num := n.Start
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "- "
+		if n.IsOrdered() {
+			marker = fmt.Sprintf("%d%c ", num, n.Marker)
+			num++
+		}
+		if !n.IsTight && len(out) > 0 {
+			out = append(out, "")
+		}
+
+		lines := w.children(item, !n.IsTight)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		pad := strings.Repeat(" ", len(marker))
+		for i, line := range lines {
+			switch {
+			case i == 0:
+				line = marker + line
+			case line != "":
+				line = pad + line
+			}
+			out = append(out, strings.TrimRight(line, " "))
+		}
+	}
+	return out
+}
+
+// inline returns the text of n's inline children.
+func (w *textWriter) inline(n ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.WriteString(unescapeText(c.Segment.Value(w.source)))
+			switch {
+			case c.HardLineBreak(), c.SoftLineBreak():
+				b.WriteString("\n")
+			}
+		case *ast.String:
+			b.WriteString(unescapeText(c.Value))
+		case *ast.CodeSpan:
+			for t := c.FirstChild(); t != nil; t = t.NextSibling() {
+				if s, ok := t.(*ast.Text); ok {
+					b.Write(s.Segment.Value(w.source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink:
+			b.Write(c.URL(w.source))
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *east.TaskCheckBox:
+			if c.IsChecked {
+				b.WriteString("[x] ")
+			} else {
+				b.WriteString("[ ] ")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// unescapeText resolves backslash escapes and character references.
+func unescapeText(s []byte) string {
+	return html.UnescapeString(string(util.UnescapePunctuations(s)))
+}
@@ -0,0 +1,110 @@
+// Package resource names markdown files as MCP resource URIs of the form
+// markdown://<root>/<path>?format=<format>, where root is a configured
+// resource root and path is relative to it.
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// Scheme is the URI scheme of markdown resources.
+const Scheme = "markdown"
+
+// Template is the RFC 6570 template matching every resource URI.
+const Template = Scheme + "://{root}/{+path}{?format}"
+
+// Formats a resource can be read in.
+const (
+	FormatHTML    = "html"
+	FormatText    = "text"
+	FormatOutline = "outline"
+)
+
+// Formats lists the formats in the order they are documented.
+var Formats = []string{FormatHTML, FormatText, FormatOutline}
+
+// mimeTypes maps each format to the MIME type of its contents.
+var mimeTypes = map[string]string{
+	FormatHTML:    "text/html",
+	FormatText:    "text/plain",
+	FormatOutline: "application/json",
+}
+
+// ErrInvalidURI is returned for URIs that do not name a markdown resource.
+var ErrInvalidURI = errors.New("invalid resource URI")
+
+// URI identifies one rendering of a markdown file.
+type URI struct {
+	Root string
+	// Path is relative to the root, slash-separated.
+	Path   string
+	Format string
+}
+
+// Parse parses a resource URI. The format query parameter takes a format
+// name or its MIME type and defaults to html.
+func Parse(raw string) (URI, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return URI{}, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	switch {
+	case u.Scheme != Scheme:
+		return URI{}, fmt.Errorf("%w: scheme must be %s", ErrInvalidURI, Scheme)
+	case u.Opaque != "" || u.User != nil || u.Port() != "" || u.Fragment != "":
+		return URI{}, fmt.Errorf("%w: %s", ErrInvalidURI, raw)
+	case u.Hostname() == "":
+		return URI{}, fmt.Errorf("%w: missing root", ErrInvalidURI)
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if path == "" {
+		return URI{}, fmt.Errorf("%w: missing path", ErrInvalidURI)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return URI{}, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	format, err := ParseFormat(query.Get("format"))
+	if err != nil {
+		return URI{}, err
+	}
+	return URI{Root: u.Hostname(), Path: path, Format: format}, nil
+}
+
+// ParseFormat returns the format named by s, a format name or MIME type.
+// Empty means html.
+func ParseFormat(s string) (string, error) {
+	if s == "" {
+		return FormatHTML, nil
+	}
+	if _, ok := mimeTypes[s]; ok {
+		return s, nil
+	}
+	if media, _, err := mime.ParseMediaType(s); err == nil {
+		for format, mt := range mimeTypes {
+			if media == mt {
+				return format, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported format %q, want one of %s", ErrInvalidURI, s, strings.Join(Formats, ", "))
+}
+
+// MimeType returns the MIME type of format's contents.
+func MimeType(format string) string {
+	return mimeTypes[format]
+}
+
+// String returns the URI, omitting the default format.
+func (u URI) String() string {
+	out := url.URL{Scheme: Scheme, Host: u.Root, Path: "/" + u.Path}
+	if u.Format != "" && u.Format != FormatHTML {
+		out.RawQuery = url.Values{"format": {u.Format}}.Encode()
+	}
+	return out.String()
+}
@@ -0,0 +1,282 @@
+// Package schema holds the JSON Schemas of the markdown request and
+// result types, used to describe and validate MCP tool calls.
+package schema
+
+import (
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// Formats lists the output formats a render request accepts.
+var Formats = []string{types.FormatHTML, types.FormatText, types.FormatBlocks, types.FormatDocument}
+
+// RawHTMLModes lists the values of RenderOptions.RawHTML.
+var RawHTMLModes = []string{types.RawHTMLAllow, types.RawHTMLEscape, types.RawHTMLDrop}
+
+// Object returns an object schema with the given properties. Properties
+// not listed are rejected.
+func Object(description string, props map[string]any, required ...string) map[string]any {
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if description != "" {
+		s["description"] = description
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// Map returns a schema for an object with arbitrary keys whose values
+// match values.
+func Map(description string, values map[string]any) map[string]any {
+	return map[string]any{"type": "object", "description": description, "additionalProperties": values}
+}
+
+// Array returns an array schema whose elements match items.
+func Array(description string, items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "description": description, "items": items}
+}
+
+// String returns a string schema.
+func String(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+// Enum returns a string schema restricted to values.
+func Enum(description string, values ...string) map[string]any {
+	return map[string]any{"type": "string", "description": description, "enum": values}
+}
+
+// Boolean returns a boolean schema.
+func Boolean(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
+
+// Integer returns an integer schema with an inclusive minimum.
+func Integer(description string, minimum int) map[string]any {
+	return map[string]any{"type": "integer", "description": description, "minimum": minimum}
+}
+
+// ── Requests ─────────────────────────────────────────────────────
+
+// RenderRequest describes types.RenderRequest.
+func RenderRequest() map[string]any {
+	return Object("Render request", renderRequestProperties(), "content")
+}
+
+// BatchItem describes types.BatchItem.
+func BatchItem() map[string]any {
+	props := renderRequestProperties()
+	props["id"] = String("Caller-chosen item identifier")
+	return Object("Batch item", props, "content")
+}
+
+// renderRequestProperties are the fields of types.RenderRequest.
+func renderRequestProperties() map[string]any {
+	return map[string]any{
+		"content": String("Markdown content to render"),
+		"format":  Enum("Output format", Formats...),
+		"options": RenderOptions(),
+		"profile": String("Named render profile, e.g. chat, docs, email, untrusted"),
+		"previous_blocks": Array(
+			"Block hashes from the previous render; with format blocks only changed blocks are returned",
+			String("Block hash")),
+	}
+}
+
+// RenderOptions describes types.RenderOptions.
+func RenderOptions() map[string]any {
+	return Object("Render options. Only raw_html may be set per request; any other option fails with invalid_options, since the settings come from the config or profile.", map[string]any{
+		"sanitize_html":  Boolean("Sanitize the rendered HTML"),
+		"enable_mermaid": Boolean("Render mermaid fences for client-side drawing"),
+		"enable_math":    Boolean("Render math fences for client-side typesetting"),
+		"enable_toc":     Boolean("Extract a table of contents"),
+		"code_theme":     Enum("Chroma style for highlighted code; empty means the default", append([]string{""}, styles.Names()...)...),
+		"raw_html":       Enum("Raw HTML handling; may only tighten the profile's mode", RawHTMLModes...),
+		"code_classes":   Boolean("Highlight code with CSS classes instead of inline styles"),
+		"sanitize":       SanitizePolicy(),
+		"limits":         Limits(),
+	})
+}
+
+// SanitizePolicy describes types.SanitizePolicy.
+func SanitizePolicy() map[string]any {
+	return Object("Sanitizer policy", map[string]any{
+		"allow_styles":     Boolean("Keep inline styles reduced to allowed properties"),
+		"style_properties": Array("CSS properties kept when allow_styles is set", String("CSS property name")),
+		"links": Object("External link policy", map[string]any{
+			"harden_external": Boolean(`Add rel="nofollow noopener noreferrer" to external links`),
+			"target_blank":    Boolean("Open external links in a new tab"),
+			"trusted_hosts":   Array("Hosts treated as internal; a *. prefix matches subdomains", String("Host name")),
+		}),
+		"id_prefix": String("Prefix for ids, names and fragment links"),
+		"max_depth": Integer("Maximum element nesting; 0 disables the limit", 0),
+	})
+}
+
+// Limits describes types.Limits.
+func Limits() map[string]any {
+	return Object("Structural limits; 0 disables a limit", map[string]any{
+		"max_depth":           Integer("Maximum block nesting depth", 0),
+		"max_nodes":           Integer("Maximum parsed nodes", 0),
+		"max_links":           Integer("Maximum rendered links", 0),
+		"max_table_cells":     Integer("Maximum table cells", 0),
+		"max_code_block_size": Integer("Maximum bytes per code block", 0),
+	})
+}
+
+// ── Results ──────────────────────────────────────────────────────
+
+// RenderResult describes types.RenderResult.
+func RenderResult() map[string]any {
+	return Object("Render result", map[string]any{
+		"html":        String("Rendered HTML"),
+		"toc":         Array("Table of contents", TOCEntry()),
+		"metadata":    Map("Frontmatter key-value pairs", String("Frontmatter value")),
+		"code_blocks": Array("Fenced code blocks", CodeBlock()),
+		"truncations": Array("Limits that cut the output short", Truncation()),
+		"warnings":    Array("Problems in the source that did not stop the render", Diagnostic()),
+		"blocks":      Array("Top-level blocks, for format blocks", BlockRef()),
+		"block_ops":   Array("Changes against previous_blocks, for format blocks", BlockOp()),
+		"text":        String("Plain-text rendering, for format text"),
+		"file":        FileInfo(),
+	}, "html")
+}
+
+// FileInfo describes types.FileInfo.
+func FileInfo() map[string]any {
+	modTime := String("Last modification time, RFC 3339")
+	modTime["format"] = "date-time"
+	return Object("Workspace file the result was read from", map[string]any{
+		"path":     String("Path relative to the workspace root"),
+		"size":     Integer("Size in bytes", 0),
+		"mod_time": modTime,
+		"hash":     String("Hex SHA-256 of the contents"),
+	}, "path", "size", "mod_time", "hash")
+}
+
+// TOCEntry describes types.TOCEntry.
+func TOCEntry() map[string]any {
+	return Object("Table of contents entry", map[string]any{
+		"level": Integer("Heading level, 1 to 6", 1),
+		"text":  String("Heading text"),
+		"id":    String("Heading anchor ID"),
+	}, "level", "text", "id")
+}
+
+// CodeBlock describes types.CodeBlock.
+func CodeBlock() map[string]any {
+	return Object("Fenced code block", map[string]any{
+		"language":   String("Info string language, empty when none"),
+		"code":       String("Block contents"),
+		"line_count": Integer("Number of lines", 0),
+	}, "language", "code", "line_count")
+}
+
+// Truncation describes types.Truncation.
+func Truncation() map[string]any {
+	return Object("Limit that cut the output short", map[string]any{
+		"limit": Enum("Limit name", types.LimitDepth, types.LimitNodes, types.LimitLinks,
+			types.LimitTableCells, types.LimitCodeBlockSize),
+		"max":    Integer("Configured maximum", 0),
+		"actual": Integer("Count before truncating", 0),
+	}, "limit", "max", "actual")
+}
+
+// Diagnostic describes types.Diagnostic.
+func Diagnostic() map[string]any {
+	position := Object("Source position", map[string]any{
+		"line":   Integer("1-based line", 1),
+		"column": Integer("1-based byte column", 1),
+		"offset": Integer("Byte offset", 0),
+	}, "line", "column", "offset")
+
+	return Object("Source diagnostic", map[string]any{
+		"severity": Enum("Severity", types.SeverityWarning, types.SeverityInfo),
+		"code": Enum("Diagnostic code",
+			types.DiagFrontmatterMalformed, types.DiagFrontmatterUnclosed, types.DiagUnclosedFence,
+			types.DiagDuplicateHeadingID, types.DiagUnknownCodeLanguage, types.DiagBrokenReferenceLink,
+			types.DiagUnknownCodeTheme, types.DiagFenceRenderFailed),
+		"message": String("Human-readable description"),
+		"range": Object("Source range, end exclusive", map[string]any{
+			"start": position,
+			"end":   position,
+		}, "start", "end"),
+	}, "severity", "code", "message")
+}
+
+// BlockRef describes types.BlockRef.
+func BlockRef() map[string]any {
+	return Object("Top-level block", map[string]any{
+		"id":   String("Block ID, stable while the block is unchanged"),
+		"hash": String("Block content hash"),
+	}, "id", "hash")
+}
+
+// BlockOp describes types.BlockOp.
+func BlockOp() map[string]any {
+	return Object("Block change", map[string]any{
+		"op":       Enum("Operation", types.BlockInsert, types.BlockRemove, types.BlockReplace),
+		"index":    Integer("Position in the new render", 0),
+		"id":       String("Block ID"),
+		"hash":     String("Block content hash"),
+		"html":     String("Block HTML, for insert and replace"),
+		"replaces": String("ID of the replaced block"),
+	}, "op", "index", "id")
+}
+
+// BlockPatch describes types.BlockPatch.
+func BlockPatch() map[string]any {
+	return Object("Replacement HTML for one streamed block", map[string]any{
+		"index": Integer("Block position", 0),
+		"html":  String("Block HTML"),
+		"final": Boolean("The block will not change again"),
+	}, "index", "html", "final")
+}
+
+// StreamUpdate describes types.StreamUpdate.
+func StreamUpdate() map[string]any {
+	return Object("Blocks changed by one streamed chunk", map[string]any{
+		"patches": Array("Changed blocks", BlockPatch()),
+		"blocks":  Integer("Total block count; clients drop blocks at or beyond it", 0),
+		"done":    Boolean("The stream is finished"),
+	}, "patches", "blocks")
+}
+
+// CacheStats describes cache.Stats.
+func CacheStats() map[string]any {
+	return Object("Render cache counters", map[string]any{
+		"hits":      Integer("Lookups served from the cache", 0),
+		"misses":    Integer("Lookups that rendered", 0),
+		"evictions": Integer("Entries dropped for space or age", 0),
+		"entries":   Integer("Entries held", 0),
+		"capacity":  Integer("Maximum entries", 0),
+		"bytes":     Integer("Approximate size of the held results in bytes", 0),
+		"max_bytes": Integer("Maximum bytes; 0 when only entries are bounded", 0),
+	}, "hits", "misses", "evictions", "entries", "capacity", "bytes", "max_bytes")
+}
+
+// Error describes the body of a failed REST request.
+func Error() map[string]any {
+	return Object("Error response", map[string]any{
+		"error":   String("Stable error code, e.g. invalid_input or input_too_large"),
+		"message": String("Human-readable description"),
+		"details": Map("Structured details, depending on the code", map[string]any{}),
+	}, "error", "message")
+}
+
+// BatchItemResult describes types.BatchItemResult.
+func BatchItemResult() map[string]any {
+	return Object("Outcome of one batch item", map[string]any{
+		"index":   Integer("Position in the request", 0),
+		"id":      String("Caller-chosen item identifier"),
+		"result":  RenderResult(),
+		"error":   String("Error message when the item failed"),
+		"code":    String("Error code when the item failed"),
+		"details": Map("Error details", map[string]any{}),
+	}, "index")
+}
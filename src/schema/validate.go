@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// FieldError is one value that does not match its schema. Path locates
+// it in the input, e.g. "items[2].format"; it is empty for the root.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every mismatch found in a value.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		if fe.Path == "" {
+			msgs[i] = fe.Message
+		} else {
+			msgs[i] = fe.Path + ": " + fe.Message
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks value, as decoded from JSON, against schema. It
+// supports the keywords the schemas in this package use: type,
+// properties, required, additionalProperties, items, enum, minimum,
+// maximum, minLength, minItems and maxItems.
+func Validate(schema map[string]any, value any) error {
+	v := &validator{}
+	v.check("", schema, value)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// maxListedEnum is the largest enum spelled out in error messages;
+// longer ones, such as code themes, are left to the schema.
+const maxListedEnum = 10
+
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(path string, schema map[string]any, value any) {
+	if t, ok := schema["type"].(string); ok && !hasType(value, t) {
+		v.fail(path, "must be %s, got %s", article(t), typeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]string); ok {
+		if s, _ := value.(string); !slices.Contains(enum, s) {
+			if len(enum) > maxListedEnum {
+				v.fail(path, "%q is not an allowed value", s)
+			} else {
+				v.fail(path, "must be one of %s", strings.Join(enum, ", "))
+			}
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.object(path, schema, val)
+	case []any:
+		if n, ok := intKeyword(schema, "minItems"); ok && len(val) < n {
+			v.fail(path, "must have at least %d items", n)
+		}
+		if n, ok := intKeyword(schema, "maxItems"); ok && len(val) > n {
+			v.fail(path, "must have at most %d items", n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range val {
+				v.check(fmt.Sprintf("%s[%d]", path, i), items, item)
+			}
+		}
+	case string:
+		if n, ok := intKeyword(schema, "minLength"); ok && len(val) < n {
+			v.fail(path, "must be at least %d characters", n)
+		}
+	default:
+		if f, ok := number(value); ok {
+			if n, ok := intKeyword(schema, "minimum"); ok && f < float64(n) {
+				v.fail(path, "must be at least %d", n)
+			}
+			if n, ok := intKeyword(schema, "maximum"); ok && f > float64(n) {
+				v.fail(path, "must be at most %d", n)
+			}
+		}
+	}
+}
+
+func (v *validator) object(path string, schema map[string]any, obj map[string]any) {
+	props, _ := schema["properties"].(map[string]any)
+	if required, ok := schema["required"].([]string); ok {
+		for _, name := range required {
+			if _, ok := obj[name]; !ok {
+				v.fail(join(path, name), "is required")
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		if prop, ok := props[name].(map[string]any); ok {
+			v.check(join(path, name), prop, obj[name])
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(join(path, name), "is not a known property")
+			}
+		case map[string]any:
+			v.check(join(path, name), extra, obj[name])
+		}
+	}
+}
+
+// join appends a property name to path.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// hasType reports whether value is of JSON Schema type t.
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := number(value)
+		return ok
+	case "integer":
+		f, ok := number(value)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// number converts the numeric types JSON decoders produce to float64.
+func number(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// intKeyword reads an integer-valued keyword such as minimum.
+func intKeyword(schema map[string]any, key string) (int, bool) {
+	n, ok := schema[key].(int)
+	return n, ok
+}
+
+// typeName names the JSON type of value for error messages.
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := number(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// article prefixes a type name with "a" or "an".
+func article(t string) string {
+	if strings.ContainsRune("aeiou", rune(t[0])) {
+		return "an " + t
+	}
+	return "a " + t
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// defaultBatchWorkers is used when Options.BatchWorkers is not set.
+const defaultBatchWorkers = 4
+
+// RenderBatch renders items in parallel on a bounded worker pool. Each
+// item gets its own result or error; a failing item never aborts the
+// batch. An error is returned only when the batch itself is too large.
+func (s *MarkdownService) RenderBatch(ctx context.Context, items []types.BatchItem) ([]types.BatchItemResult, error) {
+	if s.maxBatchSize > 0 && len(items) > s.maxBatchSize {
+		return nil, &Error{
+			Code:    CodeBatchTooLarge,
+			Message: fmt.Sprintf("batch of %d items exceeds maximum of %d", len(items), s.maxBatchSize),
+			Details: map[string]any{"limit": s.maxBatchSize, "actual": len(items)},
+		}
+	}
+
+	results := make([]types.BatchItemResult, len(items))
+	jobs := make(chan int)
+
+	workers := s.batchWorkers
+	if workers <= 0 {
+		workers = defaultBatchWorkers
+	}
+	workers = min(workers, len(items))
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.renderItem(ctx, i, items[i])
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// renderItem renders a single batch item, converting errors and panics
+// into a per-item error.
+func (s *MarkdownService) renderItem(ctx context.Context, i int, item types.BatchItem) (res types.BatchItemResult) {
+	res = types.BatchItemResult{Index: i, ID: item.ID}
+	defer func() {
+		if r := recover(); r != nil {
+			res.Result = nil
+			res.Error = fmt.Sprintf("render panicked: %v", r)
+			res.Code = CodeInternal
+		}
+	}()
+
+	result, err := s.RenderContext(ctx, item.RenderRequest)
+	if err != nil {
+		e := AsError(err)
+		res.Error, res.Code, res.Details = e.Message, e.Code, e.Details
+		return res
+	}
+	res.Result = result
+	return res
+}
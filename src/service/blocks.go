@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// maxDiffCells bounds the LCS table used to diff changed block ranges.
+// Larger ranges are replaced wholesale.
+const maxDiffCells = 1 << 20
+
+// renderBlocks renders req in block mode: every top-level block is
+// rendered and sanitized on its own, hashed, and diffed against
+// req.PreviousBlocks so only inserted, removed, or changed blocks carry
+// HTML.
+func (s *MarkdownService) renderBlocks(ctx context.Context, e *engine, p *parser.MarkdownParser, req types.RenderRequest) (*types.RenderResult, error) {
+	input := []byte(req.Content)
+	rendered, err := p.RenderBlocks(ctx, input)
+	if err != nil {
+		return nil, contextError(ctx, renderError(err))
+	}
+	blocks := rendered.HTML
+
+	hashes := make([]string, len(blocks))
+	for i, html := range blocks {
+		if e.sanitize {
+			if html, err = e.sanitizer.SanitizeContext(ctx, html); err != nil {
+				return nil, contextError(ctx, renderError(err))
+			}
+			blocks[i] = html
+		}
+		sum := sha256.Sum256([]byte(html))
+		hashes[i] = hex.EncodeToString(sum[:8])
+	}
+
+	result := p.Extract(input)
+	e.prefixTOC(result.TOC)
+	result.Truncations = rendered.Truncations
+	result.Warnings = parser.MergeDiagnostics(result.Warnings, rendered.Warnings)
+	result.Blocks = blockRefs(hashes)
+	result.BlockOps = diffBlocks(blockRefs(req.PreviousBlocks), result.Blocks, blocks)
+	return result, nil
+}
+
+// blockRefs assigns IDs to hashes. Repeated hashes get an occurrence
+// suffix so every ID in a render is unique.
+func blockRefs(hashes []string) []types.BlockRef {
+	seen := make(map[string]int, len(hashes))
+	refs := make([]types.BlockRef, len(hashes))
+	for i, h := range hashes {
+		id := "blk-" + h
+		if n := seen[h]; n > 0 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		seen[h]++
+		refs[i] = types.BlockRef{ID: id, Hash: h}
+	}
+	return refs
+}
+
+// diffBlocks returns the operations turning prev into next, comparing
+// blocks by ID so an ID always names the same DOM node. Unchanged
+// prefixes and suffixes are skipped; the middle is aligned with an LCS
+// so moved-around edits only touch the blocks that differ. Adjacent
+// removals and insertions are paired into replacements.
+func diffBlocks(prev, next []types.BlockRef, html []string) []types.BlockOp {
+	start := 0
+	for start < len(prev) && start < len(next) && prev[start].ID == next[start].ID {
+		start++
+	}
+	endP, endN := len(prev), len(next)
+	for endP > start && endN > start && prev[endP-1].ID == next[endN-1].ID {
+		endP--
+		endN--
+	}
+
+	a, b := prev[start:endP], next[start:endN]
+	keepA, keepB := alignBlocks(a, b)
+
+	var ops []types.BlockOp
+	var removed []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && !keepA[i]:
+			removed = append(removed, a[i].ID)
+			i++
+		case j < len(b) && !keepB[j]:
+			ref := next[start+j]
+			op := types.BlockOp{Op: types.BlockInsert, Index: start + j, ID: ref.ID, Hash: ref.Hash, HTML: html[start+j]}
+			if len(removed) > 0 {
+				op.Op, op.Replaces = types.BlockReplace, removed[0]
+				removed = removed[1:]
+			}
+			ops = append(ops, op)
+			j++
+		default:
+			ops = appendRemovals(ops, removed)
+			removed = nil
+			i++
+			j++
+		}
+	}
+	return appendRemovals(ops, removed)
+}
+
+// appendRemovals adds a remove operation for each ID.
+func appendRemovals(ops []types.BlockOp, ids []string) []types.BlockOp {
+	for _, id := range ids {
+		ops = append(ops, types.BlockOp{Op: types.BlockRemove, ID: id})
+	}
+	return ops
+}
+
+// alignBlocks marks the blocks of a and b that belong to their longest
+// common subsequence. Ranges too large to align are left unmatched.
+func alignBlocks(a, b []types.BlockRef) (keepA, keepB []bool) {
+	keepA, keepB = make([]bool, len(a)), make([]bool, len(b))
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxDiffCells {
+		return keepA, keepB
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].ID == b[j].ID {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].ID == b[j].ID:
+			keepA[i], keepB[j] = true, true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return keepA, keepB
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+)
+
+// Error codes carried by *Error. They are stable identifiers for clients;
+// the REST API maps each to an HTTP status.
+const (
+	CodeInvalidInput      = "invalid_input"
+	CodeInvalidOptions    = "invalid_options"
+	CodeUnsupportedFormat = "unsupported_format"
+	CodeInputTooLarge     = "input_too_large"
+	CodeBatchTooLarge     = "batch_too_large"
+	CodeLimitExceeded     = "limit_exceeded"
+	CodeTimeout           = "timeout"
+	CodeCanceled          = "canceled"
+	CodeStreamClosed      = "stream_closed"
+	CodeInternal          = "internal"
+)
+
+// Sentinels for errors.Is. Any *Error matches the sentinel with its code.
+var (
+	ErrInvalidInput      = &Error{Code: CodeInvalidInput, Message: "invalid input"}
+	ErrInvalidOptions    = &Error{Code: CodeInvalidOptions, Message: "invalid options"}
+	ErrUnsupportedFormat = &Error{Code: CodeUnsupportedFormat, Message: "unsupported format"}
+	ErrInputTooLarge     = &Error{Code: CodeInputTooLarge, Message: "input too large"}
+	ErrBatchTooLarge     = &Error{Code: CodeBatchTooLarge, Message: "batch too large"}
+	ErrLimitExceeded     = &Error{Code: CodeLimitExceeded, Message: "structural limit exceeded"}
+	ErrTimeout           = &Error{Code: CodeTimeout, Message: "render timed out"}
+	ErrCanceled          = &Error{Code: CodeCanceled, Message: "render canceled"}
+	ErrStreamClosed      = &Error{Code: CodeStreamClosed, Message: "stream is closed"}
+	ErrInternal          = &Error{Code: CodeInternal, Message: "internal error"}
+)
+
+// Error is a service failure with a stable code and structured details,
+// such as the limit and actual size for oversized input.
+type Error struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+
+	// Err is the underlying cause, if any.
+	Err error `json:"-"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is an *Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// AsError returns err as an *Error, classifying anything else as
+// internal.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: CodeInternal, Message: err.Error(), Err: err}
+}
+
+// TimeoutError reports that an operation exceeded the service's
+// per-render time budget.
+type TimeoutError struct {
+	Budget time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("render exceeded time budget of %s", e.Budget)
+}
+
+// inputTooLarge reports input of size bytes over the limit.
+func inputTooLarge(what string, limit, size int) *Error {
+	return &Error{
+		Code:    CodeInputTooLarge,
+		Message: fmt.Sprintf("%s exceeds maximum size of %d bytes", what, limit),
+		Details: map[string]any{"limit": limit, "actual": size},
+	}
+}
+
+// invalidOptions reports a bad value for a render option.
+func invalidOptions(option string, value any, allowed []string) *Error {
+	return &Error{
+		Code:    CodeInvalidOptions,
+		Message: fmt.Sprintf("invalid %s %q", option, value),
+		Details: map[string]any{"option": option, "value": value, "allowed": allowed},
+	}
+}
+
+// renderError classifies a parser failure: structural limits become
+// limit_exceeded, anything else is internal.
+func renderError(err error) *Error {
+	var limit *parser.LimitError
+	if errors.As(err, &limit) {
+		return &Error{
+			Code:    CodeLimitExceeded,
+			Message: limit.Error(),
+			Details: map[string]any{"limit": limit.Limit, "max": limit.Max, "actual": limit.Actual},
+			Err:     err,
+		}
+	}
+	return &Error{Code: CodeInternal, Message: "render failed: " + err.Error(), Err: err}
+}
+
+// contextError returns the cause of ctx ending if it is done, so budget
+// expiry surfaces as a timeout wrapping *TimeoutError and caller
+// cancellation as canceled wrapping context.Canceled. Otherwise err is
+// returned unchanged.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	cause := context.Cause(ctx)
+	var timeout *TimeoutError
+	switch {
+	case errors.As(cause, &timeout):
+		return &Error{
+			Code:    CodeTimeout,
+			Message: cause.Error(),
+			Details: map[string]any{"budget_ms": timeout.Budget.Milliseconds()},
+			Err:     cause,
+		}
+	case errors.Is(cause, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Message: "render deadline exceeded", Err: cause}
+	default:
+		return &Error{Code: CodeCanceled, Message: "render canceled", Err: cause}
+	}
+}
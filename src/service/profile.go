@@ -0,0 +1,162 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// Profile is a named bundle of render settings selected per request via
+// RenderRequest.Profile.
+type Profile struct {
+	Options  types.RenderOptions
+	Sanitize bool
+}
+
+// engine is the parser and sanitizer pair one profile renders with.
+type engine struct {
+	parser    *parser.MarkdownParser
+	sanitizer *parser.HTMLSanitizer
+	sanitize  bool
+
+	mu       sync.Mutex
+	variants map[variant]*parser.MarkdownParser
+}
+
+func newEngine(p *parser.MarkdownParser, sanitize bool) *engine {
+	return &engine{
+		parser:    p,
+		sanitizer: parser.NewSanitizerWithPolicy(sanitizePolicy(p.Options())),
+		sanitize:  sanitize,
+	}
+}
+
+// Profiles returns the names of the configured profiles, sorted.
+func (s *MarkdownService) Profiles() []string {
+	return slices.Sorted(maps.Keys(s.profiles))
+}
+
+// engineFor returns the engine for req's profile; the empty profile is
+// the service's default settings. Only raw_html may be set per request;
+// any other option fails rather than being ignored.
+func (s *MarkdownService) engineFor(req types.RenderRequest) (*engine, error) {
+	if option := requestOverride(req.Options); option != "" {
+		details := map[string]any{"option": option, "allowed": []string{"raw_html"}}
+		if req.Profile != "" {
+			details["profile"] = req.Profile
+		}
+		return nil, &Error{
+			Code:    CodeInvalidOptions,
+			Message: fmt.Sprintf("option %s cannot be set per request; only raw_html applies, the rest comes from the config or profile", option),
+			Details: details,
+		}
+	}
+	if req.Profile == "" {
+		return s.engine, nil
+	}
+	e, ok := s.profiles[req.Profile]
+	if !ok {
+		return nil, invalidOptions("profile", req.Profile, s.Profiles())
+	}
+	return e, nil
+}
+
+// CheckRequest reports the error rendering req would fail with because
+// of its profile or options, without rendering it.
+func (s *MarkdownService) CheckRequest(req types.RenderRequest) error {
+	e, err := s.engineFor(req)
+	if err != nil {
+		return err
+	}
+	_, err = e.parserFor(req)
+	return err
+}
+
+// requestOverride returns the JSON name of the first request option
+// other than raw_html that is set, or "".
+func requestOverride(opts types.RenderOptions) string {
+	switch {
+	case opts.SanitizeHTML:
+		return "sanitize_html"
+	case opts.EnableMermaid:
+		return "enable_mermaid"
+	case opts.EnableMath:
+		return "enable_math"
+	case opts.EnableTOC:
+		return "enable_toc"
+	case opts.CodeTheme != "":
+		return "code_theme"
+	case opts.CodeClasses:
+		return "code_classes"
+	case !reflect.ValueOf(opts.Sanitize).IsZero():
+		return "sanitize"
+	case opts.Limits != (types.Limits{}):
+		return "limits"
+	}
+	return ""
+}
+
+// variant identifies a parser derived from an engine's base parser.
+type variant struct {
+	rawHTML     string
+	codeClasses bool
+}
+
+// parserFor returns the parser honoring req's raw HTML mode, with
+// class-based highlighting for documents, building and caching a variant
+// of the base parser when it differs. A request may tighten the raw HTML
+// mode but never loosen it back to allow.
+func (e *engine) parserFor(req types.RenderRequest) (*parser.MarkdownParser, error) {
+	base := e.parser.Options()
+	want := variant{rawHTML: rawHTMLMode(base), codeClasses: base.CodeClasses}
+	if mode := req.Options.RawHTML; mode != "" && mode != want.rawHTML {
+		allowed := []string{types.RawHTMLEscape, types.RawHTMLDrop}
+		if want.rawHTML == types.RawHTMLAllow {
+			allowed = append(allowed, types.RawHTMLAllow)
+		}
+		if !slices.Contains(allowed, mode) {
+			return nil, invalidOptions("raw_html", mode, allowed)
+		}
+		want.rawHTML = mode
+	}
+	if req.Format == types.FormatDocument {
+		want.codeClasses = true
+	}
+	if want == (variant{rawHTML: rawHTMLMode(base), codeClasses: base.CodeClasses}) {
+		return e.parser, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if p, ok := e.variants[want]; ok {
+		return p, nil
+	}
+	if e.variants == nil {
+		e.variants = make(map[variant]*parser.MarkdownParser)
+	}
+	base.RawHTML = want.rawHTML
+	base.CodeClasses = want.codeClasses
+	p := parser.NewWithRegistry(base, e.parser.Registry())
+	e.variants[want] = p
+	return p, nil
+}
+
+// rawHTMLMode returns the effective raw HTML mode of opts.
+func rawHTMLMode(opts types.RenderOptions) string {
+	if opts.RawHTML == "" {
+		return types.RawHTMLAllow
+	}
+	return opts.RawHTML
+}
+
+// prefixTOC rewrites TOC IDs to match the prefixed heading ids.
+func (e *engine) prefixTOC(toc []types.TOCEntry) {
+	for i := range toc {
+		toc[i].ID = e.sanitizer.PrefixID(toc[i].ID)
+	}
+}
@@ -1,52 +1,331 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/orchestra-mcp/markdown/src/cache"
+	"github.com/orchestra-mcp/markdown/src/document"
 	"github.com/orchestra-mcp/markdown/src/parser"
 	"github.com/orchestra-mcp/markdown/src/types"
 )
 
 // MarkdownService provides the full markdown rendering pipeline.
 type MarkdownService struct {
-	parser       *parser.MarkdownParser
-	sanitizer    *parser.HTMLSanitizer
+	engine       *engine
+	profiles     map[string]*engine
 	maxInputSize int
-	sanitize     bool
+	timeout      time.Duration
+	cache        *cache.LRU[*types.RenderResult]
+	batchWorkers int
+	maxBatchSize int
+
+	maxStreamSize int
+	streamTimeout time.Duration
+}
+
+// Options configures a MarkdownService.
+type Options struct {
+	Sanitize     bool
+	MaxInputSize int
+	// RenderTimeout bounds each render, TOC, or code block extraction.
+	// Zero means no budget beyond the caller's context.
+	RenderTimeout time.Duration
+	// CacheSize is the number of render results kept in memory, keyed
+	// by content and effective options. Zero disables the cache.
+	CacheSize int
+	// CacheTTL bounds how long a cached result is reused.
+	CacheTTL time.Duration
+	// CacheMaxBytes bounds the approximate size of the cached results.
+	// Zero leaves only the CacheSize bound.
+	CacheMaxBytes int64
+	// BatchWorkers is the number of concurrent renders in RenderBatch.
+	BatchWorkers int
+	// MaxBatchSize caps the number of items in one batch. Zero means
+	// no limit.
+	MaxBatchSize int
+	// MaxStreamSize bounds the total input a BlockRenderer or RenderTo
+	// reads, where MaxInputSize only bounds each block. Zero means no
+	// limit.
+	MaxStreamSize int
+	// StreamTimeout bounds a whole BlockRenderer or RenderTo, where
+	// RenderTimeout only bounds each block. Zero means no budget beyond
+	// the caller's context.
+	StreamTimeout time.Duration
+	// Profiles are named setting bundles requests may select instead of
+	// the service's own parser and Sanitize setting.
+	Profiles map[string]Profile
 }
 
+// supportedFormats lists the RenderRequest formats the service renders;
+// the empty format means HTML.
+var supportedFormats = []string{"", types.FormatHTML, types.FormatText, types.FormatBlocks, types.FormatDocument}
+
 // New creates a MarkdownService with the given parser, sanitizer, and limits.
 func New(p *parser.MarkdownParser, sanitize bool, maxInputSize int) *MarkdownService {
-	return &MarkdownService{
-		parser:       p,
-		sanitizer:    parser.NewSanitizer(),
-		maxInputSize: maxInputSize,
-		sanitize:     sanitize,
+	return NewWithOptions(p, Options{Sanitize: sanitize, MaxInputSize: maxInputSize})
+}
+
+// NewWithOptions creates a MarkdownService with the given parser and options.
+func NewWithOptions(p *parser.MarkdownParser, opts Options) *MarkdownService {
+	s := &MarkdownService{
+		engine:       newEngine(p, opts.Sanitize),
+		profiles:     make(map[string]*engine, len(opts.Profiles)),
+		maxInputSize: opts.MaxInputSize,
+		timeout:      opts.RenderTimeout,
+		batchWorkers: opts.BatchWorkers,
+		maxBatchSize: opts.MaxBatchSize,
+
+		maxStreamSize: opts.MaxStreamSize,
+		streamTimeout: opts.StreamTimeout,
+	}
+	for name, profile := range opts.Profiles {
+		s.profiles[name] = newEngine(parser.NewWithRegistry(profile.Options, p.Registry()), profile.Sanitize)
+	}
+	if opts.CacheSize > 0 {
+		s.cache = cache.NewSized(opts.CacheSize, opts.CacheMaxBytes, opts.CacheTTL, resultSize)
+	}
+	return s
+}
+
+// sanitizePolicy returns the sanitizer policy for parser options. Unless
+// set explicitly, the HTML depth cap follows the markdown one: each
+// nesting level renders as up to two elements (ul > li), plus room for
+// the inline markup inside.
+func sanitizePolicy(opts types.RenderOptions) types.SanitizePolicy {
+	policy := opts.Sanitize
+	if policy.MaxDepth == 0 && opts.Limits.MaxDepth > 0 {
+		policy.MaxDepth = 2*opts.Limits.MaxDepth + 16
 	}
+	return policy
 }
 
 // Render executes the full pipeline: validate, parse, sanitize, extract.
 func (s *MarkdownService) Render(req types.RenderRequest) (*types.RenderResult, error) {
+	return s.RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Parsing, highlighting, and
+// sanitizing stop once ctx is done or the render budget is spent.
+func (s *MarkdownService) RenderContext(ctx context.Context, req types.RenderRequest) (*types.RenderResult, error) {
+	return s.render(ctx, req, s.cache != nil)
+}
+
+// render runs the pipeline, consulting the cache only when cached is set.
+func (s *MarkdownService) render(ctx context.Context, req types.RenderRequest, cached bool) (*types.RenderResult, error) {
 	if len(req.Content) == 0 {
 		return &types.RenderResult{HTML: ""}, nil
 	}
 
 	if s.maxInputSize > 0 && len(req.Content) > s.maxInputSize {
-		return nil, fmt.Errorf("input exceeds maximum size of %d bytes", s.maxInputSize)
+		return nil, inputTooLarge("input", s.maxInputSize, len(req.Content))
+	}
+	if !slices.Contains(supportedFormats, req.Format) {
+		return nil, &Error{
+			Code:    CodeUnsupportedFormat,
+			Message: fmt.Sprintf("unsupported format %q", req.Format),
+			Details: map[string]any{"format": req.Format, "supported": supportedFormats[1:]},
+		}
 	}
 
-	result, err := s.parser.Render([]byte(req.Content))
+	e, err := s.engineFor(req)
 	if err != nil {
-		return nil, fmt.Errorf("render failed: %w", err)
+		return nil, err
 	}
+	p, err := e.parserFor(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Format == types.FormatBlocks {
+		ctx, cancel := s.withBudget(ctx)
+		defer cancel()
+		return s.renderBlocks(ctx, e, p, req)
+	}
+
+	var key string
+	if cached {
+		key = cacheKey(e, p, req)
+		if cached, ok := s.cache.Get(key); ok {
+			return cloneResult(cached), nil
+		}
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	var result *types.RenderResult
+	switch req.Format {
+	case types.FormatText:
+		result, err = renderText(ctx, e, p, req)
+	case types.FormatDocument:
+		result, err = renderDocument(ctx, e, p, req)
+	default:
+		result, err = renderHTML(ctx, e, p, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		s.cache.Put(key, cloneResult(result))
+	}
+	return result, nil
+}
+
+// renderHTML renders req to HTML, sanitized when e says so.
+func renderHTML(ctx context.Context, e *engine, p *parser.MarkdownParser, req types.RenderRequest) (*types.RenderResult, error) {
+	result, err := p.RenderContext(ctx, []byte(req.Content))
+	if err != nil {
+		return nil, contextError(ctx, renderError(err))
+	}
+
+	if e.sanitize {
+		result.HTML, err = e.sanitizer.SanitizeContext(ctx, result.HTML)
+		if err != nil {
+			return nil, contextError(ctx, renderError(err))
+		}
+	}
+	e.prefixTOC(result.TOC)
+	return result, nil
+}
+
+// renderText renders req as plain text. Raw HTML never reaches the
+// output, so there is nothing to sanitize.
+func renderText(ctx context.Context, e *engine, p *parser.MarkdownParser, req types.RenderRequest) (*types.RenderResult, error) {
+	input := []byte(req.Content)
+	text, truncs, err := p.RenderText(ctx, input)
+	if err != nil {
+		return nil, contextError(ctx, renderError(err))
+	}
+
+	result := p.Extract(input)
+	e.prefixTOC(result.TOC)
+	result.Text = text
+	result.Truncations = truncs
+	return result, nil
+}
 
-	if s.sanitize {
-		result.HTML = s.sanitizer.Sanitize(result.HTML)
+// renderDocument renders req as a standalone HTML page titled from the
+// frontmatter or first heading, with the TOC as a sidebar.
+func renderDocument(ctx context.Context, e *engine, p *parser.MarkdownParser, req types.RenderRequest) (*types.RenderResult, error) {
+	result, err := renderHTML(ctx, e, p, req)
+	if err != nil {
+		return nil, err
 	}
 
+	result.HTML, err = document.Render(document.Page{
+		Title:       document.Title(result.Metadata, result.TOC, "Untitled"),
+		Description: result.Metadata["description"],
+		Lang:        result.Metadata["lang"],
+		Body:        result.HTML,
+		TOC:         result.TOC,
+		CodeTheme:   p.Options().CodeTheme,
+	})
+	if err != nil {
+		return nil, renderError(err)
+	}
 	return result, nil
 }
 
+// CacheKey returns the content address of req: a hash of its content
+// and the options it would be rendered with. Equal keys yield equal
+// results, so the key doubles as an ETag.
+func (s *MarkdownService) CacheKey(req types.RenderRequest) (string, error) {
+	e, err := s.engineFor(req)
+	if err != nil {
+		return "", err
+	}
+	p, err := e.parserFor(req)
+	if err != nil {
+		return "", err
+	}
+	return cacheKey(e, p, req), nil
+}
+
+// CacheStats reports render cache counters. It returns zero stats when
+// the cache is disabled.
+func (s *MarkdownService) CacheStats() cache.Stats {
+	if s.cache == nil {
+		return cache.Stats{}
+	}
+	return s.cache.Stats()
+}
+
+// cacheKey hashes the request content with the effective options of
+// engine e and its parser p. Profiles with equal settings share entries.
+// Block renders also hash PreviousBlocks, which decide their block_ops.
+func cacheKey(e *engine, p *parser.MarkdownParser, req types.RenderRequest) string {
+	opts, _ := json.Marshal(struct {
+		Options    types.RenderOptions `json:"options"`
+		Sanitize   bool                `json:"sanitize"`
+		Format     string              `json:"format"`
+		Extensions uint64              `json:"extensions"`
+	}{p.Options(), e.sanitize, req.Format, p.Registry().Version()})
+
+	h := sha256.New()
+	h.Write(opts)
+	h.Write([]byte{0})
+	h.Write([]byte(req.Content))
+	if req.Format == types.FormatBlocks {
+		for _, b := range req.PreviousBlocks {
+			fmt.Fprintf(h, "\x00%d:%s", len(b), b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cloneResult copies a result so cached entries are never shared with
+// callers that might modify them.
+func cloneResult(r *types.RenderResult) *types.RenderResult {
+	c := *r
+	c.TOC = slices.Clone(r.TOC)
+	c.CodeBlocks = slices.Clone(r.CodeBlocks)
+	c.Metadata = maps.Clone(r.Metadata)
+	c.Truncations = slices.Clone(r.Truncations)
+	c.Warnings = slices.Clone(r.Warnings)
+	return &c
+}
+
+// resultSize approximates the memory held by r: its strings, without
+// slice and map overhead.
+func resultSize(r *types.RenderResult) int64 {
+	n := len(r.HTML) + len(r.Text)
+	for _, e := range r.TOC {
+		n += len(e.Text) + len(e.ID)
+	}
+	for k, v := range r.Metadata {
+		n += len(k) + len(v)
+	}
+	for _, b := range r.CodeBlocks {
+		n += len(b.Language) + len(b.Code)
+	}
+	for _, w := range r.Warnings {
+		n += len(w.Message)
+	}
+	for _, b := range r.Blocks {
+		n += len(b.ID) + len(b.Hash)
+	}
+	for _, op := range r.BlockOps {
+		n += len(op.ID) + len(op.Hash) + len(op.HTML)
+	}
+	return int64(n)
+}
+
+// withBudget derives a context bounded by the service's render timeout.
+func (s *MarkdownService) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, s.timeout, &TimeoutError{Budget: s.timeout})
+}
+
 // RenderString is a convenience method: string in, HTML string out.
 func (s *MarkdownService) RenderString(content string) (string, error) {
 	result, err := s.Render(types.RenderRequest{Content: content})
@@ -58,22 +337,47 @@ func (s *MarkdownService) RenderString(content string) (string, error) {
 
 // ExtractTOC returns the table of contents for the given markdown.
 func (s *MarkdownService) ExtractTOC(content string) ([]types.TOCEntry, error) {
+	return s.ExtractTOCContext(context.Background(), content)
+}
+
+// ExtractTOCContext is ExtractTOC with cancellation.
+func (s *MarkdownService) ExtractTOCContext(ctx context.Context, content string) ([]types.TOCEntry, error) {
 	if len(content) == 0 {
 		return nil, nil
 	}
 	if s.maxInputSize > 0 && len(content) > s.maxInputSize {
-		return nil, fmt.Errorf("input exceeds maximum size of %d bytes", s.maxInputSize)
+		return nil, inputTooLarge("input", s.maxInputSize, len(content))
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+	toc, err := s.engine.parser.ExtractTOCContext(ctx, []byte(content))
+	if err != nil {
+		return nil, contextError(ctx, err)
 	}
-	return s.parser.ExtractTOC([]byte(content)), nil
+	s.engine.prefixTOC(toc)
+	return toc, nil
 }
 
 // ExtractCodeBlocks returns all fenced code blocks from the given markdown.
 func (s *MarkdownService) ExtractCodeBlocks(content string) ([]types.CodeBlock, error) {
+	return s.ExtractCodeBlocksContext(context.Background(), content)
+}
+
+// ExtractCodeBlocksContext is ExtractCodeBlocks with cancellation.
+func (s *MarkdownService) ExtractCodeBlocksContext(ctx context.Context, content string) ([]types.CodeBlock, error) {
 	if len(content) == 0 {
 		return nil, nil
 	}
 	if s.maxInputSize > 0 && len(content) > s.maxInputSize {
-		return nil, fmt.Errorf("input exceeds maximum size of %d bytes", s.maxInputSize)
+		return nil, inputTooLarge("input", s.maxInputSize, len(content))
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+	blocks, err := s.engine.parser.ExtractCodeBlocksContext(ctx, []byte(content))
+	if err != nil {
+		return nil, contextError(ctx, err)
 	}
-	return s.parser.ExtractCodeBlocks([]byte(content)), nil
+	return blocks, nil
 }
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// Stream incrementally renders markdown that arrives in chunks, such as
+// an LLM token stream. Blocks that can no longer change are rendered
+// once; only the trailing block is re-rendered on each chunk, after
+// repairing constructs the stream has not terminated yet.
+type Stream struct {
+	svc  *MarkdownService
+	opts types.RenderOptions
+
+	mu     sync.Mutex
+	src    []byte
+	offset int      // start of the source not yet rendered as final blocks
+	final  []string // HTML of the final blocks
+	tail   string   // HTML last sent for the trailing block
+	closed bool
+}
+
+// NewStream starts an incremental render using opts for every block.
+func (s *MarkdownService) NewStream(opts types.RenderOptions) *Stream {
+	return &Stream{svc: s, opts: opts}
+}
+
+// Append adds chunk to the document and returns patches for the blocks
+// whose HTML changed. A failed Append leaves the stream as it was, so the
+// chunk can be sent again.
+func (st *Stream) Append(ctx context.Context, chunk string) (*types.StreamUpdate, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return nil, ErrStreamClosed
+	}
+	if limit := st.svc.maxInputSize; limit > 0 && len(st.src)+len(chunk) > limit {
+		return nil, inputTooLarge("input", limit, len(st.src)+len(chunk))
+	}
+	src := append(st.src[:len(st.src):len(st.src)], chunk...)
+
+	pending := string(src[st.offset:])
+	stable, tail := parser.SplitStable(pending)
+
+	finals := make([]string, 0, len(stable))
+	for _, block := range stable {
+		html, err := st.renderBlock(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		finals = append(finals, html)
+	}
+	var tailHTML string
+	if strings.TrimSpace(tail) != "" {
+		html, err := st.renderBlock(ctx, parser.RepairPartial(tail))
+		if err != nil {
+			return nil, err
+		}
+		tailHTML = html
+	}
+
+	update := &types.StreamUpdate{}
+	for _, html := range finals {
+		update.Patches = append(update.Patches, types.BlockPatch{Index: len(st.final), HTML: html, Final: true})
+		st.final = append(st.final, html)
+	}
+	if len(stable) > 0 {
+		st.offset += len(pending) - len(tail)
+		st.tail = ""
+	}
+	if tailHTML != "" && (tailHTML != st.tail || len(stable) > 0) {
+		update.Patches = append(update.Patches, types.BlockPatch{Index: len(st.final), HTML: tailHTML})
+	}
+	st.src, st.tail = src, tailHTML
+
+	update.Blocks = st.blocks()
+	return update, nil
+}
+
+// Close marks the stream complete and finalizes the trailing block.
+func (st *Stream) Close(ctx context.Context) (*types.StreamUpdate, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return nil, ErrStreamClosed
+	}
+
+	update := &types.StreamUpdate{Done: true}
+	if tail := string(st.src[st.offset:]); strings.TrimSpace(tail) != "" {
+		html, err := st.renderBlock(ctx, parser.RepairPartial(tail))
+		if err != nil {
+			return nil, err
+		}
+		update.Patches = append(update.Patches, types.BlockPatch{Index: len(st.final), HTML: html, Final: true})
+		st.final = append(st.final, html)
+		st.offset = len(st.src)
+		st.tail = ""
+	}
+	st.closed = true
+	update.Blocks = st.blocks()
+	return update, nil
+}
+
+// Snapshot returns patches for every block rendered so far, for clients
+// that join a stream after it started.
+func (st *Stream) Snapshot() *types.StreamUpdate {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	update := &types.StreamUpdate{Done: st.closed}
+	for i, html := range st.final {
+		update.Patches = append(update.Patches, types.BlockPatch{Index: i, HTML: html, Final: true})
+	}
+	if st.tail != "" {
+		update.Patches = append(update.Patches, types.BlockPatch{Index: len(st.final), HTML: st.tail})
+	}
+	update.Blocks = st.blocks()
+	return update
+}
+
+// blocks returns the number of blocks currently displayed.
+func (st *Stream) blocks() int {
+	if st.tail != "" {
+		return len(st.final) + 1
+	}
+	return len(st.final)
+}
+
+// renderBlock renders one block through the service pipeline without
+// the cache, since streamed fragments are rarely requested twice.
+func (st *Stream) renderBlock(ctx context.Context, block string) (string, error) {
+	result, err := st.svc.render(ctx, types.RenderRequest{Content: block, Options: st.opts}, false)
+	if err != nil {
+		return "", err
+	}
+	return result.HTML, nil
+}
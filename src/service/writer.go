@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+// renderToChunkSize is how much input RenderTo reads at a time.
+const renderToChunkSize = 32 << 10
+
+// RenderTo reads markdown from r and writes HTML to w one top-level
+// block at a time, so memory is bounded by the largest block rather
+// than the document. Writers with a Flush method are flushed after every
+// block. MaxInputSize and RenderTimeout apply to each block, while
+// MaxStreamSize and StreamTimeout bound the whole input. Each block is rendered on its own: reference links
+// defined in a different block do not resolve, and no TOC or metadata
+// is produced.
+func (s *MarkdownService) RenderTo(ctx context.Context, r io.Reader, w io.Writer, opts types.RenderOptions) error {
+	br := s.NewBlockRenderer(ctx, r, opts)
+	for {
+		html, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeBlock(w, html); err != nil {
+			return err
+		}
+	}
+}
+
+// BlockRenderer renders markdown read from a reader one top-level block
+// at a time, as RenderTo does. Callers that must see the first block or
+// its error before writing anything, such as an HTTP handler choosing
+// its status, pull the blocks themselves.
+type BlockRenderer struct {
+	s      *MarkdownService
+	ctx    context.Context
+	cancel context.CancelFunc
+	r      io.Reader
+	opts   types.RenderOptions
+
+	buf     []byte
+	pending []byte
+	queue   []string
+	total   int
+	eof     bool
+	err     error
+}
+
+// NewBlockRenderer returns a BlockRenderer reading from r. The
+// service's StreamTimeout starts now and is released once Next returns
+// an error or io.EOF.
+func (s *MarkdownService) NewBlockRenderer(ctx context.Context, r io.Reader, opts types.RenderOptions) *BlockRenderer {
+	var cancel context.CancelFunc
+	if s.streamTimeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, s.streamTimeout, &TimeoutError{Budget: s.streamTimeout})
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	return &BlockRenderer{s: s, ctx: ctx, cancel: cancel, r: r, opts: opts, buf: make([]byte, renderToChunkSize)}
+}
+
+// Next returns the HTML of the next block that renders to anything, or
+// io.EOF after the last one. Once it fails, it keeps returning the same
+// error.
+func (b *BlockRenderer) Next() (string, error) {
+	for {
+		if len(b.queue) > 0 {
+			block := b.queue[0]
+			b.queue = b.queue[1:]
+			html, err := b.s.renderBlock(b.ctx, block, b.opts)
+			if err != nil {
+				err = contextError(b.ctx, err)
+				b.queue, b.err = nil, err
+				b.cancel()
+				return "", err
+			}
+			if html != "" {
+				return html, nil
+			}
+			continue
+		}
+		if b.err != nil {
+			b.cancel()
+			return "", b.err
+		}
+		if b.eof {
+			b.err = io.EOF
+			if len(b.pending) > 0 {
+				b.queue = append(b.queue, string(b.pending))
+				b.pending = nil
+			}
+			continue
+		}
+		b.fill()
+	}
+}
+
+// fill reads the next chunk, queueing the blocks that became stable. A
+// tail over MaxInputSize or a total over MaxStreamSize fails once the
+// queued blocks are rendered.
+func (b *BlockRenderer) fill() {
+	if err := b.ctx.Err(); err != nil {
+		b.err = contextError(b.ctx, err)
+		return
+	}
+	n, err := b.r.Read(b.buf)
+	if n > 0 {
+		b.total += n
+		if max := b.s.maxStreamSize; max > 0 && b.total > max {
+			b.err = inputTooLarge("input", max, b.total)
+			return
+		}
+		b.pending = append(b.pending, b.buf[:n]...)
+		stable, tail := parser.SplitStable(string(b.pending))
+		b.queue = append(b.queue, stable...)
+		if max := b.s.maxInputSize; max > 0 && len(tail) > max {
+			b.err = inputTooLarge("block", max, len(tail))
+			return
+		}
+		b.pending = append(b.pending[:0], tail...)
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		b.eof = true
+	case err != nil:
+		b.err = err
+	}
+}
+
+// renderBlock renders one block to HTML.
+func (s *MarkdownService) renderBlock(ctx context.Context, block string, opts types.RenderOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	result, err := s.render(ctx, types.RenderRequest{Content: block, Options: opts}, false)
+	if err != nil {
+		return "", err
+	}
+	return result.HTML, nil
+}
+
+// writeBlock writes the HTML of one block to w and flushes it.
+func writeBlock(w io.Writer, html string) error {
+	if _, err := io.WriteString(w, html+"\n"); err != nil {
+		return err
+	}
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// flusher is implemented by buffered writers such as *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
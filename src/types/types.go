@@ -1,10 +1,65 @@
 package types
 
+import "time"
+
 // RenderRequest represents a request to render markdown content.
 type RenderRequest struct {
 	Content string        `json:"content"`
-	Format  string        `json:"format"` // "html", "text", "ast"
+	Format  string        `json:"format"` // "html", "text", "ast", "blocks", "document"
 	Options RenderOptions `json:"options"`
+
+	// Profile selects a named bundle of settings (e.g. "chat", "docs")
+	// configured on the service. Empty uses the service defaults.
+	Profile string `json:"profile,omitempty"`
+
+	// PreviousBlocks holds the block hashes of the previous render, in
+	// order. With format "blocks" only blocks that differ are returned.
+	PreviousBlocks []string `json:"previous_blocks,omitempty"`
+}
+
+// Output formats for RenderRequest.Format.
+const (
+	FormatHTML   = "html"
+	FormatText   = "text"
+	FormatAST    = "ast"
+	FormatBlocks = "blocks"
+	// FormatDocument renders a complete, styled HTML page in
+	// RenderResult.HTML.
+	FormatDocument = "document"
+)
+
+// BatchItem is one document in a batch render request.
+type BatchItem struct {
+	ID string `json:"id,omitempty"`
+	RenderRequest
+}
+
+// BatchItemResult is the outcome of rendering one BatchItem. Exactly one
+// of Result and Error is set; failures also carry the error code and
+// details.
+type BatchItemResult struct {
+	Index   int            `json:"index"`
+	ID      string         `json:"id,omitempty"`
+	Result  *RenderResult  `json:"result,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Code    string         `json:"code,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// BlockPatch replaces the HTML of one top-level block of a streamed
+// render. Final blocks never change again.
+type BlockPatch struct {
+	Index int    `json:"index"`
+	HTML  string `json:"html"`
+	Final bool   `json:"final"`
+}
+
+// StreamUpdate lists the blocks changed by one streamed chunk. Blocks is
+// the total block count; clients drop any block at or beyond it.
+type StreamUpdate struct {
+	Patches []BlockPatch `json:"patches"`
+	Blocks  int          `json:"blocks"`
+	Done    bool         `json:"done,omitempty"`
 }
 
 // RenderOptions configures how markdown is rendered.
@@ -14,6 +69,91 @@ type RenderOptions struct {
 	EnableMath    bool   `json:"enable_math"`
 	EnableTOC     bool   `json:"enable_toc"`
 	CodeTheme     string `json:"code_theme"`
+	RawHTML       string `json:"raw_html,omitempty"` // "allow", "escape", "drop"
+
+	// CodeClasses highlights code with CSS classes instead of inline
+	// styles; the page must then include the theme's stylesheet.
+	CodeClasses bool `json:"code_classes,omitempty"`
+
+	Sanitize SanitizePolicy `json:"sanitize"`
+	Limits   Limits         `json:"limits"`
+}
+
+// Limits bounds the structure of a document so pathological input
+// cannot make a render arbitrarily expensive. Zero disables a limit.
+// Exceeding MaxDepth or MaxNodes fails the render; the other limits
+// truncate the output and are reported in RenderResult.Truncations.
+type Limits struct {
+	// MaxDepth caps block nesting (quotes and lists) in the source and
+	// element nesting in the rendered HTML.
+	MaxDepth int `json:"max_depth,omitempty"`
+	// MaxNodes caps the number of nodes in the parsed document.
+	MaxNodes int `json:"max_nodes,omitempty"`
+	// MaxLinks caps rendered links; later links render as plain text.
+	MaxLinks int `json:"max_links,omitempty"`
+	// MaxTableCells caps table cells across the document; rows past
+	// the limit are dropped.
+	MaxTableCells int `json:"max_table_cells,omitempty"`
+	// MaxCodeBlockSize caps the bytes of each code block; longer
+	// blocks are cut at a line boundary.
+	MaxCodeBlockSize int `json:"max_code_block_size,omitempty"`
+}
+
+// Limit names used in Truncation.Limit and parser limit errors.
+const (
+	LimitDepth         = "max_depth"
+	LimitNodes         = "max_nodes"
+	LimitLinks         = "max_links"
+	LimitTableCells    = "max_table_cells"
+	LimitCodeBlockSize = "max_code_block_size"
+)
+
+// Truncation reports output dropped because a limit was exceeded.
+// Actual is the count the document had before truncating, or the
+// largest block size for code blocks.
+type Truncation struct {
+	Limit  string `json:"limit"`
+	Max    int    `json:"max"`
+	Actual int    `json:"actual"`
+}
+
+// Raw HTML modes for RenderOptions.RawHTML.
+const (
+	RawHTMLAllow  = "allow"
+	RawHTMLEscape = "escape"
+	RawHTMLDrop   = "drop"
+)
+
+// SanitizePolicy tunes the HTML sanitizer beyond its fixed tag allowlist.
+type SanitizePolicy struct {
+	// AllowStyles keeps inline style attributes, reduced to the
+	// declarations whose property is in StyleProperties.
+	AllowStyles bool `json:"allow_styles"`
+	// StyleProperties overrides the default CSS property allowlist.
+	StyleProperties []string `json:"style_properties,omitempty"`
+
+	Links LinkPolicy `json:"links"`
+
+	// IDPrefix is prepended to every id and name attribute and to
+	// fragment links (e.g. "user-content-") to prevent DOM clobbering.
+	IDPrefix string `json:"id_prefix,omitempty"`
+
+	// MaxDepth caps element nesting. Deeper input is rejected rather
+	// than walked. Zero means no limit.
+	MaxDepth int `json:"max_depth,omitempty"`
+}
+
+// LinkPolicy controls how anchors pointing outside the trusted hosts
+// are rewritten during sanitization.
+type LinkPolicy struct {
+	// HardenExternal adds rel="nofollow noopener noreferrer" to
+	// external links.
+	HardenExternal bool `json:"harden_external"`
+	// TargetBlank additionally opens external links in a new tab.
+	TargetBlank bool `json:"target_blank"`
+	// TrustedHosts are hosts treated as internal. A "*." prefix also
+	// matches any subdomain.
+	TrustedHosts []string `json:"trusted_hosts,omitempty"`
 }
 
 // RenderResult holds the output of a markdown render operation.
@@ -22,6 +162,102 @@ type RenderResult struct {
 	TOC        []TOCEntry        `json:"toc,omitempty"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	CodeBlocks []CodeBlock       `json:"code_blocks,omitempty"`
+
+	// Truncations lists the limits that cut the output short.
+	Truncations []Truncation `json:"truncations,omitempty"`
+
+	// Warnings reports problems in the source that did not stop the
+	// render, in source order.
+	Warnings []Diagnostic `json:"warnings,omitempty"`
+
+	// Blocks lists every top-level block in order and BlockOps the
+	// changes against PreviousBlocks. Set only for format "blocks".
+	Blocks   []BlockRef `json:"blocks,omitempty"`
+	BlockOps []BlockOp  `json:"block_ops,omitempty"`
+
+	// Text is the plain-text rendering, set only for format "text";
+	// HTML is then empty.
+	Text string `json:"text,omitempty"`
+
+	// File describes the workspace file the result was rendered from.
+	File *FileInfo `json:"file,omitempty"`
+}
+
+// FileInfo identifies the version of a workspace file that was read.
+// Path is relative to the workspace root, slash-separated; Hash is the
+// hex SHA-256 of the contents.
+type FileInfo struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// Diagnostic severities.
+const (
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Diagnostic codes.
+const (
+	DiagFrontmatterMalformed = "frontmatter_malformed"
+	DiagFrontmatterUnclosed  = "frontmatter_unclosed"
+	DiagUnclosedFence        = "unclosed_fence"
+	DiagDuplicateHeadingID   = "duplicate_heading_id"
+	DiagUnknownCodeLanguage  = "unknown_code_language"
+	DiagBrokenReferenceLink  = "broken_reference_link"
+	DiagUnknownCodeTheme     = "unknown_code_theme"
+	DiagFenceRenderFailed    = "fence_render_failed"
+)
+
+// Diagnostic is a problem found in the source. Range is nil for
+// document-wide problems such as an unknown code theme.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Range    *Range `json:"range,omitempty"`
+}
+
+// Range is a span of the source, end exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is a location in the source. Line and Column are 1-based;
+// Column and Offset count bytes.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// BlockRef identifies one top-level block of a block-mode render. The ID
+// is derived from the hash, so equal blocks keep their ID across renders.
+type BlockRef struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
+}
+
+// Block diff operations for BlockOp.Op.
+const (
+	BlockInsert  = "insert"
+	BlockRemove  = "remove"
+	BlockReplace = "replace"
+)
+
+// BlockOp is one change between two block-mode renders. Clients apply
+// removals by ID first, then inserts and replaces in Index order, where
+// Index is the block's position in the new render.
+type BlockOp struct {
+	Op       string `json:"op"`
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Hash     string `json:"hash,omitempty"`
+	HTML     string `json:"html,omitempty"`
+	Replaces string `json:"replaces,omitempty"`
 }
 
 // TOCEntry represents one heading in the table of contents.
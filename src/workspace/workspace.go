@@ -0,0 +1,209 @@
+// Package workspace reads markdown files by path from a configured root
+// directory, refusing paths that would leave it.
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+)
+
+var (
+	// ErrDisabled is returned when no workspace root is configured.
+	ErrDisabled = errors.New("workspace root is not configured")
+	// ErrInvalidPath is returned for paths that are absolute, leave the
+	// root, have a disallowed extension, or do not name a regular file.
+	ErrInvalidPath = errors.New("invalid path")
+	// ErrNotFound is returned when the file does not exist.
+	ErrNotFound = errors.New("file not found")
+)
+
+// SizeError reports a file larger than the workspace allows.
+type SizeError struct {
+	Path  string
+	Limit int64
+	Size  int64
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, limit is %d", e.Path, e.Size, e.Limit)
+}
+
+// Workspace resolves slash-separated relative paths against Root.
+// Symlinks may not point outside Root.
+type Workspace struct {
+	Root string
+	// MaxSize caps the bytes read from one file. Zero means no limit.
+	MaxSize int64
+	// Extensions lists the allowed file extensions, lowercase with the
+	// leading dot. Empty allows any extension.
+	Extensions []string
+}
+
+// File is a workspace file with its contents.
+type File struct {
+	Info    types.FileInfo
+	Content []byte
+}
+
+// Read returns the file at path. Errors match ErrDisabled,
+// ErrInvalidPath or ErrNotFound, or are a *SizeError.
+func (w *Workspace) Read(path string) (*File, error) {
+	name, err := w.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := os.OpenRoot(w.Root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	// O_NONBLOCK keeps a FIFO from blocking the open; anything but a
+	// regular file is rejected below.
+	f, err := root.OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPath, path, unwrapPathError(err))
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !st.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidPath, path)
+	}
+	if w.MaxSize > 0 && st.Size() > w.MaxSize {
+		return nil, &SizeError{Path: path, Limit: w.MaxSize, Size: st.Size()}
+	}
+
+	// The file may grow between Stat and reading, so the read is capped
+	// as well.
+	var r io.Reader = f
+	if w.MaxSize > 0 {
+		r = io.LimitReader(f, w.MaxSize+1)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if w.MaxSize > 0 && int64(len(content)) > w.MaxSize {
+		return nil, &SizeError{Path: path, Limit: w.MaxSize, Size: int64(len(content))}
+	}
+
+	sum := sha256.Sum256(content)
+	return &File{
+		Info: types.FileInfo{
+			Path:    filepath.ToSlash(name),
+			Size:    int64(len(content)),
+			ModTime: st.ModTime().UTC(),
+			Hash:    hex.EncodeToString(sum[:]),
+		},
+		Content: content,
+	}, nil
+}
+
+// resolve checks path and returns it cleaned, in OS form.
+func (w *Workspace) resolve(path string) (string, error) {
+	if w.Root == "" {
+		return "", ErrDisabled
+	}
+	if path == "" || strings.ContainsRune(path, '\\') || strings.ContainsRune(path, 0) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+	name := filepath.FromSlash(path)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %s is outside the workspace", ErrInvalidPath, path)
+	}
+	name = filepath.Clean(name)
+	if len(w.Extensions) > 0 && !slices.Contains(w.Extensions, strings.ToLower(filepath.Ext(name))) {
+		return "", fmt.Errorf("%w: %s must have one of the extensions %s", ErrInvalidPath, path, strings.Join(w.Extensions, ", "))
+	}
+	return name, nil
+}
+
+// unwrapPathError drops the *fs.PathError wrapper, whose message would
+// repeat the path.
+func unwrapPathError(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Err
+	}
+	return err
+}
+
+// Entry is a file found by List.
+type Entry struct {
+	// Path is relative to the root, slash-separated.
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// List returns the files under the root that Read would accept, sorted
+// by path. Hidden files and directories, symlinks, and files over
+// MaxSize are skipped. At most limit entries are returned when limit is
+// positive.
+func (w *Workspace) List(limit int) ([]Entry, error) {
+	if w.Root == "" {
+		return nil, ErrDisabled
+	}
+	root, err := os.OpenRoot(w.Root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	var entries []Entry
+	err = fs.WalkDir(root.FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(w.Extensions) > 0 && !slices.Contains(w.Extensions, strings.ToLower(path.Ext(name))) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if w.MaxSize > 0 && info.Size() > w.MaxSize {
+			return nil
+		}
+
+		entries = append(entries, Entry{Path: name, Size: info.Size(), ModTime: info.ModTime().UTC()})
+		if limit > 0 && len(entries) >= limit {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Block Diff Rendering ─────────────────────────────────────────
+
+func renderBlocks(t *testing.T, content string, prev []types.BlockRef) *types.RenderResult {
+	t.Helper()
+	hashes := make([]string, len(prev))
+	for i, b := range prev {
+		hashes[i] = b.Hash
+	}
+	result, err := newService().Render(types.RenderRequest{
+		Content:        content,
+		Format:         types.FormatBlocks,
+		PreviousBlocks: hashes,
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestBlocksInitialRenderInsertsAll(t *testing.T) {
+	result := renderBlocks(t, "# Title\n\nOne\n\nTwo\n", nil)
+
+	require.Len(t, result.Blocks, 3)
+	require.Len(t, result.BlockOps, 3)
+	for i, op := range result.BlockOps {
+		assert.Equal(t, types.BlockInsert, op.Op)
+		assert.Equal(t, i, op.Index)
+		assert.Equal(t, result.Blocks[i].ID, op.ID)
+	}
+	assert.Contains(t, result.BlockOps[1].HTML, "<p>One</p>")
+	assert.Empty(t, result.HTML)
+}
+
+func TestBlocksUnchangedRenderHasNoOps(t *testing.T) {
+	first := renderBlocks(t, "# Title\n\nOne\n", nil)
+	second := renderBlocks(t, "# Title\n\nOne\n", first.Blocks)
+
+	assert.Equal(t, first.Blocks, second.Blocks)
+	assert.Empty(t, second.BlockOps)
+}
+
+func TestBlocksChangedBlockReplaced(t *testing.T) {
+	first := renderBlocks(t, "# Title\n\nOne\n\nTwo\n", nil)
+	second := renderBlocks(t, "# Title\n\nOne!\n\nTwo\n\nThree\n", first.Blocks)
+
+	require.Len(t, second.BlockOps, 2)
+	assert.Equal(t, types.BlockReplace, second.BlockOps[0].Op)
+	assert.Equal(t, 1, second.BlockOps[0].Index)
+	assert.Equal(t, first.Blocks[1].ID, second.BlockOps[0].Replaces)
+	assert.Contains(t, second.BlockOps[0].HTML, "One!")
+	assert.Equal(t, types.BlockInsert, second.BlockOps[1].Op)
+	assert.Equal(t, 3, second.BlockOps[1].Index)
+}
+
+func TestBlocksRemoved(t *testing.T) {
+	first := renderBlocks(t, "A\n\nB\n\nC\n", nil)
+	second := renderBlocks(t, "A\n\nC\n", first.Blocks)
+
+	require.Len(t, second.BlockOps, 1)
+	assert.Equal(t, types.BlockRemove, second.BlockOps[0].Op)
+	assert.Equal(t, first.Blocks[1].ID, second.BlockOps[0].ID)
+}
+
+func TestBlocksDuplicateContentGetsUniqueIDs(t *testing.T) {
+	result := renderBlocks(t, "Same\n\nSame\n", nil)
+
+	require.Len(t, result.Blocks, 2)
+	assert.Equal(t, result.Blocks[0].Hash, result.Blocks[1].Hash)
+	assert.NotEqual(t, result.Blocks[0].ID, result.Blocks[1].ID)
+}
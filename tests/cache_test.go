@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/cache"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── LRU ──────────────────────────────────────────────────────────
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.New[string](2, 0)
+	c.Put("a", "1")
+	c.Put("b", "2")
+	_, _ = c.Get("a")
+	c.Put("c", "3")
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	c := cache.New[string](4, 10*time.Millisecond)
+	c.Put("a", "1")
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Stats().Entries)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestLRUBoundsBytes(t *testing.T) {
+	c := cache.NewSized(10, 8, 0, func(v string) int64 { return int64(len(v)) })
+	c.Put("a", "1234")
+	c.Put("b", "1234")
+	c.Put("c", "12")
+	c.Put("big", "123456789")
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	_, ok = c.Get("big")
+	assert.False(t, ok)
+	for _, key := range []string{"b", "c"} {
+		_, ok := c.Get(key)
+		assert.True(t, ok, key)
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, int64(6), stats.Bytes)
+	assert.Equal(t, int64(8), stats.MaxBytes)
+	assert.Equal(t, uint64(1), stats.Evictions)
+}
+
+// ── Render Cache ─────────────────────────────────────────────────
+
+func newCachedService() *service.MarkdownService {
+	return service.NewWithOptions(newParser(true), service.Options{
+		Sanitize:  true,
+		CacheSize: 8,
+	})
+}
+
+func TestRenderCacheHit(t *testing.T) {
+	svc := newCachedService()
+	req := types.RenderRequest{Content: "# Cached\n"}
+
+	first, err := svc.Render(req)
+	require.NoError(t, err)
+	first.HTML = "mutated"
+
+	second, err := svc.Render(req)
+	require.NoError(t, err)
+	assert.Contains(t, second.HTML, "Cached")
+
+	stats := svc.CacheStats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestCacheKeyDependsOnOptions(t *testing.T) {
+	svc := newCachedService()
+
+	a, err := svc.CacheKey(types.RenderRequest{Content: "x"})
+	require.NoError(t, err)
+	b, err := svc.CacheKey(types.RenderRequest{Content: "x"})
+	require.NoError(t, err)
+	c, err := svc.CacheKey(types.RenderRequest{
+		Content: "x",
+		Options: types.RenderOptions{RawHTML: types.RawHTMLDrop},
+	})
+	require.NoError(t, err)
+	d, err := svc.CacheKey(types.RenderRequest{Content: "y"})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.NotEqual(t, a, d)
+}
+
+func TestCacheKeyDependsOnPreviousBlocks(t *testing.T) {
+	svc := newService()
+	req := types.RenderRequest{Content: "# A\n\nb\n", Format: types.FormatBlocks}
+
+	fresh, err := svc.CacheKey(req)
+	require.NoError(t, err)
+	req.PreviousBlocks = []string{"<h1>A</h1>"}
+	known, err := svc.CacheKey(req)
+	require.NoError(t, err)
+	assert.NotEqual(t, fresh, known)
+
+	req.PreviousBlocks = []string{"<h1>A</h1>", ""}
+	padded, err := svc.CacheKey(req)
+	require.NoError(t, err)
+	assert.NotEqual(t, known, padded)
+}
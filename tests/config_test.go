@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+func configFrom(values map[string]any) func(string) (any, bool) {
+	return func(key string) (any, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+// ── Config Loading ───────────────────────────────────────────────
+
+func TestConfigLoadDefaults(t *testing.T) {
+	cfg, err := config.Load(configFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultConfig(), cfg)
+}
+
+func TestConfigLoadEveryKey(t *testing.T) {
+	values := config.DefaultConfig().Map()
+	values["enabled"] = false
+	values["sanitize_html"] = "false"
+	values["enable_mermaid"] = false
+	values["max_input_size"] = float64(2048)
+	values["render_timeout_ms"] = "500"
+	values["max_links"] = int64(7)
+	values["code_theme"] = "dracula"
+	values["raw_html"] = "escape"
+	values["trusted_hosts"] = "example.com, *.example.org"
+
+	cfg, err := config.Load(configFrom(values))
+	require.NoError(t, err)
+
+	assert.False(t, cfg.Enabled)
+	assert.False(t, cfg.SanitizeHTML)
+	assert.False(t, cfg.EnableMermaid)
+	assert.Equal(t, 2048, cfg.MaxInputSize)
+	assert.Equal(t, 500, cfg.RenderTimeoutMs)
+	assert.Equal(t, 7, cfg.MaxLinks)
+	assert.Equal(t, "dracula", cfg.CodeTheme)
+	assert.Equal(t, "escape", cfg.RawHTML)
+	assert.Equal(t, []string{"example.com", "*.example.org"}, cfg.TrustedHosts)
+	assert.Equal(t, values["id_prefix"], cfg.IDPrefix)
+}
+
+func TestConfigMapCoversEveryKey(t *testing.T) {
+	values := config.DefaultConfig().Map()
+	cfg, err := config.Load(configFrom(values))
+	require.NoError(t, err)
+	assert.Equal(t, values, cfg.Map())
+	assert.Len(t, values, 31)
+}
+
+func TestConfigLoadTypeErrors(t *testing.T) {
+	_, err := config.Load(configFrom(map[string]any{
+		"sanitize_html":  "sometimes",
+		"max_input_size": 1.5,
+		"code_theme":     42,
+		"trusted_hosts":  []any{"ok.com", 3},
+	}))
+	require.Error(t, err)
+
+	var keys []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *config.FieldError
+		require.True(t, errors.As(e, &fe))
+		keys = append(keys, fe.Key)
+	}
+	assert.ElementsMatch(t, []string{"sanitize_html", "max_input_size", "code_theme", "trusted_hosts"}, keys)
+}
+
+func TestConfigLoadLargeIntegers(t *testing.T) {
+	cfg, err := config.Load(configFrom(map[string]any{"cache_max_bytes": float64(4294967296)}))
+	require.NoError(t, err)
+	assert.EqualValues(t, int64(4294967296), cfg.CacheMaxBytes)
+
+	_, err = config.Load(configFrom(map[string]any{"cache_max_bytes": float64(32 << 30)}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be between")
+
+	_, err = config.Load(configFrom(map[string]any{"cache_max_bytes": 1e30}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not fit in an int")
+}
+
+func TestConfigValidateRanges(t *testing.T) {
+	_, err := config.Load(configFrom(map[string]any{
+		"batch_workers": 0,
+		"cache_size":    -1,
+		"code_theme":    "no-such-theme",
+		"raw_html":      "maybe",
+		"id_prefix":     "1bad prefix",
+		"trusted_hosts": []string{"https://example.com"},
+	}))
+	require.Error(t, err)
+
+	for _, key := range []string{"batch_workers", "cache_size", "code_theme", "raw_html", "id_prefix", "trusted_hosts"} {
+		assert.Contains(t, err.Error(), key+":")
+	}
+}
+
+func TestConfigResourceRoots(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := config.Load(configFrom(map[string]any{
+		"resource_roots": "docs=" + dir + ", notes = " + dir,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"docs": dir, "notes": dir}, cfg.ResourceRoots)
+
+	_, err = config.Load(configFrom(map[string]any{
+		"resource_roots": map[string]any{"Docs": dir, "rel": "docs", "gone": dir + "/missing"},
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource_roots: root names")
+	assert.Contains(t, err.Error(), "resource_roots.rel: must be an absolute path")
+	assert.Contains(t, err.Error(), "resource_roots.gone: must be an existing directory")
+
+	_, err = config.Load(configFrom(map[string]any{"resource_roots": map[string]any{"docs": 1}}))
+	assert.ErrorContains(t, err, "resource_roots: must be an object of strings")
+}
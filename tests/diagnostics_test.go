@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+func warningCodes(diags []types.Diagnostic) []string {
+	codes := make([]string, len(diags))
+	for i, d := range diags {
+		codes[i] = d.Code
+	}
+	return codes
+}
+
+// ── Diagnostics ──────────────────────────────────────────────────
+
+func TestDiagnosticsCleanDocument(t *testing.T) {
+	svc := newService()
+	result, err := svc.Render(types.RenderRequest{
+		Content: "---\ntitle: Doc\n---\n# A\n\n[x][ref]\n\n[ref]: /x\n\n```go\nx\n```\n",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestDiagnosticsFrontmatter(t *testing.T) {
+	p := newParser(false)
+
+	diags := p.Diagnose([]byte("---\ntitle: ok\nnot a pair\n---\nbody\n"))
+	require.Len(t, diags, 1)
+	assert.Equal(t, types.DiagFrontmatterMalformed, diags[0].Code)
+	assert.Equal(t, types.Position{Line: 3, Column: 1, Offset: 14}, diags[0].Range.Start)
+	assert.Equal(t, types.Position{Line: 3, Column: 11, Offset: 24}, diags[0].Range.End)
+
+	diags = p.Diagnose([]byte("---\ntitle: never closed\n"))
+	assert.Equal(t, []string{types.DiagFrontmatterUnclosed}, warningCodes(diags))
+}
+
+func TestDiagnosticsUnclosedFenceAndLanguage(t *testing.T) {
+	p := newParser(false)
+
+	diags := p.Diagnose([]byte("intro\n\n```notalanguage\ncode\n"))
+	require.Len(t, diags, 2)
+	assert.Equal(t, types.DiagUnknownCodeLanguage, diags[0].Code)
+	assert.Equal(t, types.SeverityInfo, diags[0].Severity)
+	assert.Equal(t, types.DiagUnclosedFence, diags[1].Code)
+	assert.Equal(t, 3, diags[1].Range.Start.Line)
+}
+
+func TestDiagnosticsLanguageIgnoresAttributes(t *testing.T) {
+	p := newParser(false)
+
+	for _, md := range []string{
+		"```go{.wide}\nx\n```\n",
+		"```go {#x}\nx\n```\n",
+		"```{.wide}\nx\n```\n",
+	} {
+		assert.Empty(t, p.Diagnose([]byte(md)), md)
+	}
+	diags := p.Diagnose([]byte("```nope{.wide}\nx\n```\n"))
+	require.Len(t, diags, 1)
+	assert.Contains(t, diags[0].Message, `"nope"`)
+}
+
+func TestDiagnosticsDuplicateHeadings(t *testing.T) {
+	p := newParser(false)
+
+	diags := p.Diagnose([]byte("# Setup\n\n```\n# Setup\n```\n\n## Setup\n"))
+	require.Len(t, diags, 1)
+	assert.Equal(t, types.DiagDuplicateHeadingID, diags[0].Code)
+	assert.Equal(t, 7, diags[0].Range.Start.Line)
+}
+
+func TestDiagnosticsBrokenReferences(t *testing.T) {
+	p := newParser(false)
+
+	diags := p.Diagnose([]byte("See [docs][Docs  Page], [api][] and `[a][b]`.\n\n[docs page]: /docs\n"))
+	require.Len(t, diags, 1)
+	assert.Equal(t, types.DiagBrokenReferenceLink, diags[0].Code)
+	assert.Equal(t, 25, diags[0].Range.Start.Column)
+	assert.Equal(t, 32, diags[0].Range.End.Column)
+}
+
+func TestDiagnosticsUnknownTheme(t *testing.T) {
+	p := parser.New(types.RenderOptions{CodeTheme: "no-such-theme"})
+
+	diags := p.Diagnose([]byte("text\n"))
+	require.Len(t, diags, 1)
+	assert.Equal(t, types.DiagUnknownCodeTheme, diags[0].Code)
+	assert.Nil(t, diags[0].Range)
+}
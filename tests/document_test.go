@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/document"
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Standalone Documents ─────────────────────────────────────────
+
+func TestRenderDocument(t *testing.T) {
+	src := "---\ntitle: Setup <Guide>\ndescription: How to \"install\"\nlang: en\n---\n" +
+		"# Intro\n\n## Install\n\n```go\nfunc main() {}\n```\n\n<script>alert(1)</script>\n"
+
+	result, err := newService().Render(types.RenderRequest{Content: src, Format: types.FormatDocument})
+	require.NoError(t, err)
+	page := result.HTML
+
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>\n<html lang=\"en\">"))
+	assert.Contains(t, page, "<title>Setup &lt;Guide&gt;</title>")
+	assert.Contains(t, page, `<meta name="description" content="How to &#34;install&#34;">`)
+	assert.Contains(t, page, `<li class="toc-level-2"><a href="#install">Install</a></li>`)
+	assert.Contains(t, page, "prefers-color-scheme: dark")
+	assert.Contains(t, page, document.ThemeCSS("monokai"))
+	assert.Contains(t, page, `<pre class="chroma"><code>`)
+	assert.Contains(t, page, `<span class="kd">func</span>`)
+	assert.NotContains(t, page, "<script>")
+	assert.NotContains(t, page, "title: Setup")
+	assert.Equal(t, "Setup <Guide>", result.Metadata["title"])
+}
+
+func TestRenderDocumentWithoutTOC(t *testing.T) {
+	result, err := newService().Render(types.RenderRequest{Content: "plain *text*\n", Format: types.FormatDocument})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "<title>Untitled</title>")
+	assert.Contains(t, result.HTML, "<body>\n<main class=\"markdown-body\">\n<p>plain <em>text</em></p>\n</main>")
+	assert.NotContains(t, result.HTML, `name="description"`)
+}
+
+func TestRenderHTMLKeepsInlineHighlighting(t *testing.T) {
+	svc := service.New(parser.New(types.RenderOptions{CodeTheme: "monokai"}), false, 0)
+	result, err := svc.Render(types.RenderRequest{Content: "```go\nx := 1\n```\n"})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<pre style=`)
+	assert.NotContains(t, result.HTML, "chroma")
+}
+
+func TestDocumentTitle(t *testing.T) {
+	toc := []types.TOCEntry{{Level: 2, Text: "Sub"}, {Level: 1, Text: "Main"}}
+	assert.Equal(t, "Meta", document.Title(map[string]string{"title": " Meta "}, toc, "x"))
+	assert.Equal(t, "Main", document.Title(nil, toc, "x"))
+	assert.Equal(t, "x", document.Title(nil, toc[:1], "x"))
+}
+
+// ── Frontmatter In Output ────────────────────────────────────────
+
+func TestFrontmatterNotRendered(t *testing.T) {
+	src := "---\ntitle: Doc\n---\n# Hello\n\n```nosuchlang\nx\n"
+	result, err := newService().Render(types.RenderRequest{Content: src})
+	require.NoError(t, err)
+	assert.Equal(t, "Doc", result.Metadata["title"])
+	assert.True(t, strings.HasPrefix(result.HTML, `<h1 id="hello">Hello</h1>`), result.HTML)
+	assert.NotContains(t, result.HTML, "<hr")
+
+	var unclosed *types.Diagnostic
+	for i := range result.Warnings {
+		if result.Warnings[i].Code == types.DiagUnclosedFence {
+			unclosed = &result.Warnings[i]
+		}
+	}
+	require.NotNil(t, unclosed)
+	assert.Equal(t, 6, unclosed.Range.Start.Line)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Error Codes ──────────────────────────────────────────────────
+
+func TestErrorInputTooLarge(t *testing.T) {
+	svc := service.New(newParser(false), false, 10)
+
+	_, err := svc.RenderString(strings.Repeat("x", 25))
+	require.ErrorIs(t, err, service.ErrInputTooLarge)
+
+	e := service.AsError(err)
+	assert.Equal(t, service.CodeInputTooLarge, e.Code)
+	assert.Equal(t, map[string]any{"limit": 10, "actual": 25}, e.Details)
+}
+
+func TestErrorInvalidOptions(t *testing.T) {
+	svc := newService()
+
+	_, err := svc.Render(types.RenderRequest{
+		Content: "x",
+		Options: types.RenderOptions{RawHTML: "maybe"},
+	})
+	require.ErrorIs(t, err, service.ErrInvalidOptions)
+	assert.Equal(t, "raw_html", service.AsError(err).Details["option"])
+}
+
+func TestErrorUnsupportedFormat(t *testing.T) {
+	svc := newService()
+
+	_, err := svc.Render(types.RenderRequest{Content: "x", Format: "pdf"})
+	require.ErrorIs(t, err, service.ErrUnsupportedFormat)
+	assert.Equal(t, "pdf", service.AsError(err).Details["format"])
+}
+
+func TestErrorLimitExceeded(t *testing.T) {
+	svc := service.New(parser.New(types.RenderOptions{Limits: types.Limits{MaxDepth: 2}}), false, 0)
+
+	_, err := svc.RenderString("> > > deep\n")
+	require.ErrorIs(t, err, service.ErrLimitExceeded)
+
+	var limitErr *parser.LimitError
+	assert.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, types.LimitDepth, service.AsError(err).Details["limit"])
+}
+
+func TestErrorTimeoutAndCanceled(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{RenderTimeout: time.Nanosecond})
+	md := strings.Repeat("```go\nfmt.Println(\"hello\")\n```\n\n", 200)
+
+	_, err := svc.Render(types.RenderRequest{Content: md})
+	require.ErrorIs(t, err, service.ErrTimeout)
+	assert.Equal(t, int64(0), service.AsError(err).Details["budget_ms"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = newService().RenderContext(ctx, types.RenderRequest{Content: "x"})
+	assert.ErrorIs(t, err, service.ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestErrorBatchCodes(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{MaxInputSize: 5, MaxBatchSize: 2})
+
+	_, err := svc.RenderBatch(context.Background(), make([]types.BatchItem, 3))
+	require.ErrorIs(t, err, service.ErrBatchTooLarge)
+
+	results, err := svc.RenderBatch(context.Background(), []types.BatchItem{
+		{RenderRequest: types.RenderRequest{Content: "toolong"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, service.CodeInputTooLarge, results[0].Code)
+	assert.Equal(t, 5, results[0].Details["limit"])
+}
+
+func TestErrorUnknownIsInternal(t *testing.T) {
+	e := service.AsError(errors.New("boom"))
+	assert.Equal(t, service.CodeInternal, e.Code)
+	assert.ErrorIs(t, e, service.ErrInternal)
+}
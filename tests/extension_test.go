@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	gparser "github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+// taskCard renders orchestra-task fences as a card.
+func taskCard(block parser.FenceBlock) (string, error) {
+	return `<div class="task-card">` + strings.TrimSpace(string(block.Code)) + "</div>\n", nil
+}
+
+// mentionTransformer marks paragraphs that mention @agent.
+type mentionTransformer struct{}
+
+func (mentionTransformer) Transform(doc *ast.Document, reader text.Reader, _ gparser.Context) {
+	source := reader.Source()
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if n.Kind() == ast.KindParagraph && strings.Contains(string(n.Lines().Value(source)), "@agent") {
+			n.SetAttributeString("class", []byte("mention"))
+		}
+	}
+}
+
+const fenceDoc = "```orchestra-task\nShip it\n```\n\n```go\nx := 1\n```\n"
+
+// ── Extension Registry ───────────────────────────────────────────
+
+func TestRegistryFenceRenderer(t *testing.T) {
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("orchestra-task", taskCard, 0))
+	p := parser.NewWithRegistry(types.RenderOptions{}, reg)
+
+	result, err := p.Render([]byte(fenceDoc))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<div class="task-card">Ship it</div>`)
+	// Other languages still go through the highlighter.
+	assert.Contains(t, result.HTML, "<pre")
+	assert.NotContains(t, result.HTML, "orchestra-task")
+	for _, w := range result.Warnings {
+		assert.NotEqual(t, types.DiagUnknownCodeLanguage, w.Code)
+	}
+}
+
+func TestRegistryFencePriority(t *testing.T) {
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("orchestra-task", func(parser.FenceBlock) (string, error) {
+		return "<p>low</p>", nil
+	}, 100))
+	require.NoError(t, reg.RegisterFence("orchestra-task", taskCard, 10))
+
+	result, err := parser.NewWithRegistry(types.RenderOptions{}, reg).Render([]byte(fenceDoc))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "task-card")
+	assert.NotContains(t, result.HTML, "low")
+	assert.Equal(t, []string{"orchestra-task"}, reg.Languages())
+}
+
+func TestRegistryTransformerAndExtension(t *testing.T) {
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterTransformer("mentions", mentionTransformer{}, 500))
+	require.NoError(t, reg.RegisterExtension("footnote", extension.Footnote, 0))
+	p := parser.NewWithRegistry(types.RenderOptions{}, reg)
+
+	result, err := p.Render([]byte("Ask @agent now[^1]\n\n[^1]: Note\n"))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<p class="mention">Ask @agent`)
+	assert.Contains(t, result.HTML, `class="footnotes"`)
+}
+
+func TestRegistryAppliesToLaterRenders(t *testing.T) {
+	reg := parser.NewRegistry()
+	svc := service.NewWithOptions(parser.NewWithRegistry(types.RenderOptions{}, reg), service.Options{CacheSize: 16})
+	req := types.RenderRequest{Content: fenceDoc}
+
+	before, err := svc.Render(req)
+	require.NoError(t, err)
+	assert.NotContains(t, before.HTML, "task-card")
+
+	version := reg.Version()
+	require.NoError(t, reg.RegisterFence("orchestra-task", taskCard, 0))
+	assert.Greater(t, reg.Version(), version)
+
+	after, err := svc.Render(req)
+	require.NoError(t, err)
+	assert.Contains(t, after.HTML, "task-card")
+
+	assert.True(t, reg.Unregister("orchestra-task"))
+	assert.False(t, reg.Unregister("orchestra-task"))
+	again, err := svc.Render(req)
+	require.NoError(t, err)
+	assert.NotContains(t, again.HTML, "task-card")
+}
+
+func TestRegistryRejectsEmpty(t *testing.T) {
+	reg := parser.NewRegistry()
+	assert.Error(t, reg.RegisterFence("", taskCard, 0))
+	assert.Error(t, reg.RegisterExtension("x", nil, 0))
+	assert.Error(t, reg.RegisterTransformer("", mentionTransformer{}, 0))
+	assert.Zero(t, reg.Version())
+}
+
+// ── Fence Renderers ──────────────────────────────────────────────
+
+func TestFenceAttributes(t *testing.T) {
+	reg := parser.NewRegistry()
+	var got parser.FenceBlock
+	require.NoError(t, reg.RegisterFence("chart", func(block parser.FenceBlock) (string, error) {
+		got = block
+		return "<figure></figure>", nil
+	}, 0))
+
+	md := "```chart type=bar title=\"Q1 sales\" stacked {.wide #sales}\na,1\nb,2\n```\n"
+	result, err := parser.NewWithRegistry(types.RenderOptions{}, reg).Render([]byte(md))
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTML, "<figure></figure>")
+	assert.Equal(t, "chart", got.Language)
+	assert.Equal(t, "a,1\nb,2\n", string(got.Code))
+	assert.Equal(t, map[string]string{
+		"type":    "bar",
+		"title":   "Q1 sales",
+		"stacked": "",
+		"class":   "wide",
+		"id":      "sales",
+	}, got.Attributes)
+}
+
+func TestFenceAttributesAttachedToLanguage(t *testing.T) {
+	reg := parser.NewRegistry()
+	var got parser.FenceBlock
+	require.NoError(t, reg.RegisterFence("chart", func(block parser.FenceBlock) (string, error) {
+		got = block
+		return "<figure></figure>", nil
+	}, 0))
+	p := parser.NewWithRegistry(types.RenderOptions{EnableMermaid: true}, reg)
+
+	result, err := p.Render([]byte("```chart{.wide #sales}\na,1\n```\n"))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "<figure></figure>")
+	assert.Equal(t, "chart", got.Language)
+	assert.Equal(t, map[string]string{"class": "wide", "id": "sales"}, got.Attributes)
+
+	result, err = p.Render([]byte("```mermaid{.wide}\ngraph TD\n```\n"))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<pre class="mermaid">`)
+}
+
+func TestFenceErrorFallsBackToCode(t *testing.T) {
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("orchestra-task", func(parser.FenceBlock) (string, error) {
+		return "", errors.New("bad task")
+	}, 0))
+	require.NoError(t, reg.RegisterFence("go", func(parser.FenceBlock) (string, error) {
+		panic("boom")
+	}, 0))
+
+	result, err := parser.NewWithRegistry(types.RenderOptions{}, reg).Render([]byte(fenceDoc))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "Ship it")
+	assert.Contains(t, result.HTML, "<pre")
+
+	var failed []types.Diagnostic
+	for _, w := range result.Warnings {
+		if w.Code == types.DiagFenceRenderFailed {
+			failed = append(failed, w)
+		}
+	}
+	require.Len(t, failed, 2)
+	assert.Contains(t, failed[0].Message, "bad task")
+	assert.Equal(t, 1, failed[0].Range.Start.Line)
+	assert.Contains(t, failed[1].Message, "panicked")
+	assert.Equal(t, 5, failed[1].Range.Start.Line)
+}
+
+func TestFenceBuiltinMermaidAndMath(t *testing.T) {
+	md := "```mermaid\ngraph TD; A-->B\n```\n\n```math\nx < y\n```\n"
+
+	result, err := parser.New(types.RenderOptions{EnableMermaid: true, EnableMath: true}).Render([]byte(md))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<pre class="mermaid">graph TD; A--&gt;B`)
+	assert.Contains(t, result.HTML, `<div class="math math-display">x &lt; y`)
+
+	result, err = parser.New(types.RenderOptions{}).Render([]byte(md))
+	require.NoError(t, err)
+	assert.NotContains(t, result.HTML, `class="mermaid"`)
+
+	// Registered renderers replace the built-in ones.
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("mermaid", func(parser.FenceBlock) (string, error) {
+		return "<svg></svg>", nil
+	}, 0))
+	result, err = parser.NewWithRegistry(types.RenderOptions{EnableMermaid: true}, reg).Render([]byte(md))
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "<svg></svg>")
+}
+
+func TestFenceOutputIsSanitized(t *testing.T) {
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("orchestra-task", func(parser.FenceBlock) (string, error) {
+		return `<div class="task-card" onclick="alert(1)">ok</div><script>alert(1)</script>`, nil
+	}, 0))
+	svc := service.NewWithOptions(parser.NewWithRegistry(types.RenderOptions{}, reg), service.Options{Sanitize: true})
+
+	result, err := svc.Render(types.RenderRequest{Content: fenceDoc})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, `<div class="task-card">ok</div>`)
+	assert.NotContains(t, result.HTML, "script")
+	assert.NotContains(t, result.HTML, "onclick")
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+func newLimitedService(l types.Limits) *service.MarkdownService {
+	p := parser.New(types.RenderOptions{Limits: l})
+	return service.New(p, true, 0)
+}
+
+// ── Depth and Node Limits ────────────────────────────────────────
+
+func TestLimitNestedQuotesRejectedBeforeParse(t *testing.T) {
+	svc := newLimitedService(types.Limits{MaxDepth: 100})
+
+	start := time.Now()
+	_, err := svc.RenderString(strings.Repeat(">", 100000) + " deep\n")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	var limitErr *parser.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, types.LimitDepth, limitErr.Limit)
+	assert.Equal(t, 100, limitErr.Max)
+	assert.Equal(t, 100000, limitErr.Actual)
+}
+
+func TestLimitNestedListsByIndentation(t *testing.T) {
+	var b strings.Builder
+	for i := range 8 {
+		b.WriteString(strings.Repeat("  ", i) + "- item\n")
+	}
+	svc := newLimitedService(types.Limits{MaxDepth: 5})
+
+	_, err := svc.RenderString(b.String())
+	var limitErr *parser.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, types.LimitDepth, limitErr.Limit)
+}
+
+func TestLimitDepthIgnoresFencedCode(t *testing.T) {
+	svc := newLimitedService(types.Limits{MaxDepth: 3})
+
+	html, err := svc.RenderString("```\n> > > > > quoted\n```\n")
+	require.NoError(t, err)
+	assert.Contains(t, html, "quoted")
+}
+
+func TestLimitNodes(t *testing.T) {
+	svc := newLimitedService(types.Limits{MaxNodes: 50})
+
+	_, err := svc.RenderString(strings.Repeat("para\n\n", 100))
+	var limitErr *parser.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, types.LimitNodes, limitErr.Limit)
+}
+
+func TestLimitSanitizerDepth(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{MaxDepth: 10})
+
+	_, err := s.SanitizeContext(t.Context(), strings.Repeat("<div>", 50)+"x")
+	var limitErr *parser.LimitError
+	require.True(t, errors.As(err, &limitErr))
+
+	out, err := s.SanitizeContext(t.Context(), "<div><p>ok</p></div>")
+	require.NoError(t, err)
+	assert.Equal(t, "<div><p>ok</p></div>", out)
+}
+
+// ── Truncating Limits ────────────────────────────────────────────
+
+func TestLimitLinksRenderAsText(t *testing.T) {
+	svc := newLimitedService(types.Limits{MaxLinks: 2})
+
+	result, err := svc.Render(types.RenderRequest{
+		Content: "[a](/a) [b](/b) [c](/c) <https://d.example>\n",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(result.HTML, "<a "))
+	assert.Contains(t, result.HTML, " c ")
+	assert.Contains(t, result.HTML, "https://d.example")
+	assert.Equal(t, []types.Truncation{{Limit: types.LimitLinks, Max: 2, Actual: 4}}, result.Truncations)
+}
+
+func TestLimitTableCells(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("| A | B |\n|---|---|\n")
+	for i := range 5 {
+		fmt.Fprintf(&b, "| r%d | x |\n", i)
+	}
+	svc := newLimitedService(types.Limits{MaxTableCells: 6})
+
+	result, err := svc.Render(types.RenderRequest{Content: b.String()})
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTML, "r1")
+	assert.NotContains(t, result.HTML, "r2")
+	assert.Equal(t, []types.Truncation{{Limit: types.LimitTableCells, Max: 6, Actual: 12}}, result.Truncations)
+}
+
+func TestLimitCodeBlockSize(t *testing.T) {
+	md := "```\n" + strings.Repeat("line\n", 10) + "```\n"
+	svc := newLimitedService(types.Limits{MaxCodeBlockSize: 20})
+
+	result, err := svc.Render(types.RenderRequest{Content: md})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, strings.Count(result.HTML, "line"))
+	assert.Equal(t, []types.Truncation{{Limit: types.LimitCodeBlockSize, Max: 20, Actual: 50}}, result.Truncations)
+}
+
+func TestLimitsUnsetLeaveOutputAlone(t *testing.T) {
+	svc := newService()
+
+	result, err := svc.Render(types.RenderRequest{Content: "[a](/a) [b](/b)\n"})
+	require.NoError(t, err)
+	assert.Nil(t, result.Truncations)
+}
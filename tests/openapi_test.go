@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/openapi"
+	"github.com/orchestra-mcp/markdown/src/schema"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+// openAPIDocument returns the document as decoded JSON.
+func openAPIDocument(t *testing.T) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(openapi.Document("1.2.3", "/api"))
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	return doc
+}
+
+// collectRefs returns every $ref value in v.
+func collectRefs(v any) []string {
+	var refs []string
+	switch v := v.(type) {
+	case map[string]any:
+		if r, ok := v["$ref"].(string); ok {
+			refs = append(refs, r)
+		}
+		for _, item := range v {
+			refs = append(refs, collectRefs(item)...)
+		}
+	case []any:
+		for _, item := range v {
+			refs = append(refs, collectRefs(item)...)
+		}
+	}
+	return refs
+}
+
+// ── OpenAPI ──────────────────────────────────────────────────────
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := openAPIDocument(t)
+	assert.Equal(t, "3.1.0", doc["openapi"])
+	assert.Equal(t, "1.2.3", doc["info"].(map[string]any)["version"])
+	assert.Equal(t, []any{map[string]any{"url": "/api"}}, doc["servers"])
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	refs := collectRefs(doc)
+	require.NotEmpty(t, refs)
+	for _, r := range refs {
+		name, ok := strings.CutPrefix(r, "#/components/schemas/")
+		require.True(t, ok, r)
+		assert.Contains(t, schemas, name)
+	}
+
+	ids := map[string]bool{}
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			op := op.(map[string]any)
+			id := op["operationId"].(string)
+			assert.False(t, ids[id], "duplicate operationId %s", id)
+			ids[id] = true
+			assert.NotEmpty(t, op["responses"], "%s %s", method, path)
+		}
+	}
+	assert.Len(t, ids, 14)
+}
+
+func TestOpenAPIUsesNamedSchemas(t *testing.T) {
+	doc := openAPIDocument(t)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	result := schemas["RenderResult"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/TOCEntry"}, result["toc"].(map[string]any)["items"])
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/FileInfo"}, result["file"])
+
+	render := doc["paths"].(map[string]any)["/markdown/render"].(map[string]any)["post"].(map[string]any)
+	ok := render["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/RenderResult"}, ok["application/json"].(map[string]any)["schema"])
+	assert.Contains(t, render["requestBody"].(map[string]any)["content"], "text/markdown")
+	assert.Contains(t, render["responses"], "406")
+
+	stream := doc["paths"].(map[string]any)["/markdown/render/stream"].(map[string]any)["post"].(map[string]any)
+	assert.Contains(t, stream["responses"], "413")
+}
+
+func TestOpenAPIComponentsMatchResults(t *testing.T) {
+	components := openapi.Components()
+	result, err := newService().Render(types.RenderRequest{Content: "# A\n\n```go\nx\n```\n", Format: types.FormatText})
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate(components["RenderResult"], decoded(t, result)))
+	assert.NoError(t, schema.Validate(components["RenderRequest"], decoded(t, types.RenderRequest{Content: "x", Format: "html"})))
+	assert.Error(t, schema.Validate(components["RenderRequest"], map[string]any{"format": "pdf"}))
+}
+
+func TestOpenAPIErrorCodesAreDefined(t *testing.T) {
+	known := map[string]bool{
+		service.CodeInvalidInput: true, service.CodeInvalidOptions: true,
+		service.CodeUnsupportedFormat: true, service.CodeInputTooLarge: true,
+		service.CodeBatchTooLarge: true, service.CodeLimitExceeded: true,
+		service.CodeTimeout: true, service.CodeCanceled: true,
+		service.CodeStreamClosed: true, service.CodeInternal: true,
+		// Plugin-level codes from providers/errors.go.
+		"unavailable": true, "invalid_config": true, "invalid_path": true,
+		"not_found": true, "not_acceptable": true, "unsupported_media_type": true,
+		"too_many_streams": true,
+	}
+
+	doc := openAPIDocument(t)
+	for path, item := range doc["paths"].(map[string]any) {
+		for _, op := range item.(map[string]any) {
+			for status, resp := range op.(map[string]any)["responses"].(map[string]any) {
+				if strings.HasPrefix(status, "2") || status == "304" {
+					continue
+				}
+				desc := resp.(map[string]any)["description"].(string)
+				_, codes, ok := strings.Cut(desc, ": ")
+				require.True(t, ok, "%s %s: %s", path, status, desc)
+				for _, code := range strings.FieldsFunc(codes, func(r rune) bool { return r == ',' || r == ' ' }) {
+					if code != "or" {
+						assert.True(t, known[code], "%s %s lists unknown code %s", path, status, code)
+					}
+				}
+			}
+		}
+	}
+}
@@ -177,6 +177,17 @@ func TestSanitizeIframe(t *testing.T) {
 	assert.Contains(t, result, "<p>After</p>")
 }
 
+func TestSanitizeUnwrappedChildrenCleaned(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	input := `<p><font><img src="a.png" onerror="alert(1)"><script>alert(2)</script></font></p>`
+	result := s.Sanitize(input)
+
+	assert.NotContains(t, result, "onerror")
+	assert.NotContains(t, result, "script")
+	assert.Contains(t, result, `<img src="a.png"/>`)
+}
+
 // ── Size Limit ───────────────────────────────────────────────────
 
 func TestMaxInputSize(t *testing.T) {
@@ -219,3 +230,58 @@ func TestEmptyCodeBlocks(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, blocks)
 }
+
+// ── Raw HTML Modes ───────────────────────────────────────────────
+
+func TestRawHTMLAllowedByDefault(t *testing.T) {
+	p := newParser(false)
+	result, err := p.Render([]byte("<div class=\"note\">hi</div>\n\nText <kbd>x</kbd>\n"))
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTML, `<div class="note">hi</div>`)
+	assert.Contains(t, result.HTML, "<kbd>x</kbd>")
+}
+
+func TestRawHTMLEscape(t *testing.T) {
+	p := parser.New(types.RenderOptions{RawHTML: types.RawHTMLEscape})
+	result, err := p.Render([]byte("<script>alert(1)</script>\n\nText <b onclick=\"x\">bold</b>\n"))
+	require.NoError(t, err)
+
+	assert.NotContains(t, result.HTML, "<script>")
+	assert.NotContains(t, result.HTML, "<b ")
+	assert.Contains(t, result.HTML, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.Contains(t, result.HTML, "&lt;b onclick=&quot;x&quot;&gt;bold&lt;/b&gt;")
+}
+
+func TestRawHTMLDrop(t *testing.T) {
+	p := parser.New(types.RenderOptions{RawHTML: types.RawHTMLDrop})
+	result, err := p.Render([]byte("<div>block</div>\n\nText <b>bold</b> end\n"))
+	require.NoError(t, err)
+
+	assert.NotContains(t, result.HTML, "<div>")
+	assert.NotContains(t, result.HTML, "block")
+	assert.NotContains(t, result.HTML, "<b>")
+	assert.NotContains(t, result.HTML, "raw HTML omitted")
+	assert.Contains(t, result.HTML, "<p>Text bold end</p>")
+}
+
+func TestRawHTMLPerRequest(t *testing.T) {
+	svc := service.New(newParser(false), false, 0)
+
+	result, err := svc.Render(types.RenderRequest{
+		Content: "Text <i>x</i>\n",
+		Options: types.RenderOptions{RawHTML: types.RawHTMLEscape},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "&lt;i&gt;x&lt;/i&gt;")
+
+	result, err = svc.Render(types.RenderRequest{Content: "Text <i>x</i>\n"})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "<i>x</i>")
+
+	_, err = svc.Render(types.RenderRequest{
+		Content: "x",
+		Options: types.RenderOptions{RawHTML: "maybe"},
+	})
+	assert.Error(t, err)
+}
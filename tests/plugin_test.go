@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/providers"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Plugin Services ──────────────────────────────────────────────
+
+func TestPluginServices(t *testing.T) {
+	p := providers.NewMarkdownPlugin()
+	factories := make(map[string]func() any)
+	for _, def := range p.Services() {
+		factories[def.ID] = def.Factory
+	}
+
+	require.Contains(t, factories, "markdown")
+	require.Contains(t, factories, "markdown.extensions")
+	assert.Same(t, p.Extensions(), factories["markdown.extensions"]())
+}
+
+func TestPluginReloadAfterDeactivate(t *testing.T) {
+	p := providers.NewMarkdownPlugin()
+	require.NoError(t, p.Deactivate())
+
+	err := p.Reload()
+	require.Error(t, err)
+	assert.Equal(t, "unavailable", service.AsError(err).Code)
+	assert.Nil(t, p.Service())
+	assert.Nil(t, p.Config())
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/config"
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+func newProfileService() *service.MarkdownService {
+	return service.NewWithOptions(newParser(false), service.Options{
+		Sanitize:  true,
+		CacheSize: 16,
+		Profiles: map[string]service.Profile{
+			"strict": {
+				Options:  types.RenderOptions{RawHTML: types.RawHTMLDrop},
+				Sanitize: true,
+			},
+			"docs": {
+				Options:  types.RenderOptions{EnableTOC: true, Sanitize: types.SanitizePolicy{IDPrefix: "doc-"}},
+				Sanitize: true,
+			},
+		},
+	})
+}
+
+// ── Service Profiles ─────────────────────────────────────────────
+
+func TestProfileSelectsSettings(t *testing.T) {
+	svc := newProfileService()
+	md := "# Title\n\nText <b>bold</b>\n"
+
+	result, err := svc.Render(types.RenderRequest{Content: md})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "<b>bold</b>")
+	assert.Empty(t, result.TOC)
+
+	result, err = svc.Render(types.RenderRequest{Content: md, Profile: "strict"})
+	require.NoError(t, err)
+	assert.NotContains(t, result.HTML, "<b>")
+
+	result, err = svc.Render(types.RenderRequest{Content: md, Profile: "docs"})
+	require.NoError(t, err)
+	require.Len(t, result.TOC, 1)
+	assert.Equal(t, "doc-title", result.TOC[0].ID)
+	assert.Contains(t, result.HTML, `id="doc-title"`)
+}
+
+func TestProfileUnknown(t *testing.T) {
+	svc := newProfileService()
+
+	_, err := svc.Render(types.RenderRequest{Content: "x", Profile: "nope"})
+	require.ErrorIs(t, err, service.ErrInvalidOptions)
+	assert.Equal(t, []string{"docs", "strict"}, service.AsError(err).Details["allowed"])
+	assert.Equal(t, []string{"docs", "strict"}, svc.Profiles())
+}
+
+func TestProfileCacheKeysDiffer(t *testing.T) {
+	svc := newProfileService()
+	req := types.RenderRequest{Content: "Text <b>bold</b>\n"}
+
+	base, err := svc.CacheKey(req)
+	require.NoError(t, err)
+	req.Profile = "strict"
+	strict, err := svc.CacheKey(req)
+	require.NoError(t, err)
+	assert.NotEqual(t, base, strict)
+}
+
+func TestProfileRawHTMLCannotBeLoosened(t *testing.T) {
+	svc := newProfileService()
+
+	_, err := svc.Render(types.RenderRequest{
+		Content: "<b>x</b>",
+		Profile: "strict",
+		Options: types.RenderOptions{RawHTML: types.RawHTMLAllow},
+	})
+	require.ErrorIs(t, err, service.ErrInvalidOptions)
+
+	result, err := svc.Render(types.RenderRequest{
+		Content: "Text <b>x</b>\n",
+		Profile: "strict",
+		Options: types.RenderOptions{RawHTML: types.RawHTMLEscape},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "&lt;b&gt;")
+
+	escaping := service.New(parser.New(types.RenderOptions{RawHTML: types.RawHTMLEscape}), false, 0)
+	_, err = escaping.Render(types.RenderRequest{Content: "x", Options: types.RenderOptions{RawHTML: types.RawHTMLAllow}})
+	assert.ErrorIs(t, err, service.ErrInvalidOptions)
+}
+
+func TestRequestOptionsRejected(t *testing.T) {
+	svc := newProfileService()
+
+	_, err := svc.Render(types.RenderRequest{Content: "x", Profile: "docs", Options: types.RenderOptions{EnableMath: true}})
+	require.ErrorIs(t, err, service.ErrInvalidOptions)
+	assert.Equal(t, "enable_math", service.AsError(err).Details["option"])
+
+	_, err = svc.Render(types.RenderRequest{Content: "x", Profile: "docs", Options: types.RenderOptions{Limits: types.Limits{MaxNodes: 5}}})
+	assert.Equal(t, "limits", service.AsError(err).Details["option"])
+
+	_, err = svc.Render(types.RenderRequest{Content: "x", Profile: "docs", Options: types.RenderOptions{RawHTML: types.RawHTMLEscape}})
+	assert.NoError(t, err)
+
+	// The default settings take no overrides either.
+	_, err = svc.Render(types.RenderRequest{Content: "x", Options: types.RenderOptions{CodeTheme: "dracula"}})
+	require.ErrorIs(t, err, service.ErrInvalidOptions)
+	assert.Equal(t, "code_theme", service.AsError(err).Details["option"])
+	assert.Equal(t, err, svc.CheckRequest(types.RenderRequest{Options: types.RenderOptions{CodeTheme: "dracula"}}))
+	assert.NoError(t, svc.CheckRequest(types.RenderRequest{Options: types.RenderOptions{RawHTML: types.RawHTMLDrop}}))
+}
+
+// ── Config Profiles ──────────────────────────────────────────────
+
+func TestConfigBuiltinProfiles(t *testing.T) {
+	cfg, err := config.Load(configFrom(map[string]any{"code_theme": "dracula", "id_prefix": "doc-"}))
+	require.NoError(t, err)
+
+	require.Contains(t, cfg.Profiles, "chat")
+	assert.Equal(t, "escape", cfg.Profiles["chat"].RawHTML)
+	assert.True(t, cfg.Profiles["docs"].EnableTableOfContents)
+	assert.True(t, cfg.Profiles["email"].AllowInlineStyles)
+	assert.Equal(t, "drop", cfg.Profiles["untrusted"].RawHTML)
+	// Settings a profile does not override come from the top level.
+	assert.Equal(t, "dracula", cfg.Profiles["untrusted"].CodeTheme)
+	assert.Equal(t, "doc-", cfg.Profiles["docs"].IDPrefix)
+}
+
+func TestConfigCustomProfiles(t *testing.T) {
+	cfg, err := config.Load(configFrom(map[string]any{
+		"profiles": map[string]any{
+			"chat":  map[string]any{"raw_html": "drop"},
+			"slide": map[string]any{"enable_math": "false", "code_theme": "github"},
+		},
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "drop", cfg.Profiles["chat"].RawHTML)
+	assert.Equal(t, "user-content-", cfg.Profiles["chat"].IDPrefix)
+	assert.False(t, cfg.Profiles["slide"].EnableMath)
+	assert.Equal(t, "github", cfg.Profiles["slide"].CodeTheme)
+	assert.Len(t, cfg.Profiles, 5)
+}
+
+func TestConfigProfileErrors(t *testing.T) {
+	_, err := config.Load(configFrom(map[string]any{
+		"profiles": map[string]any{
+			"Bad Name": map[string]any{},
+			"chat":     map[string]any{"raw_html": "maybe", "enable_math": 3},
+			"docs":     "not an object",
+		},
+	}))
+	require.Error(t, err)
+
+	for _, key := range []string{"profiles:", "profiles.chat.raw_html:", "profiles.chat.enable_math:", "profiles.docs:"} {
+		assert.Contains(t, err.Error(), key)
+	}
+}
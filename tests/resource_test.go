@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/resource"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Plain Text ───────────────────────────────────────────────────
+
+func TestRenderText(t *testing.T) {
+	src := "---\ntitle: Guide\n---\n# Hello *world*\n\n" +
+		"Some **bold** `a*b` and [a link](https://x.test) &amp; \\*stars\\*.\nNext <span>line</span>.\n\n" +
+		"- one\n- [x] done\n  - nested\n\n" +
+		"3. three\n4. four\n\n" +
+		"> quoted\n\n" +
+		"```go\nx := 1\n```\n\n" +
+		"| a | b |\n|---|---|\n| 1 | 2 |\n\n" +
+		"<div>dropped</div>\n\n---\n\nSee <https://auto.test>\n"
+
+	result, err := newService().Render(types.RenderRequest{Content: src, Format: types.FormatText})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello world\n\n"+
+		"Some bold a*b and a link & *stars*.\nNext line.\n\n"+
+		"- one\n- [x] done\n  - nested\n\n"+
+		"3. three\n4. four\n\n"+
+		"quoted\n\n"+
+		"x := 1\n\n"+
+		"a\tb\n1\t2\n\n"+
+		"See https://auto.test\n", result.Text)
+	assert.Empty(t, result.HTML)
+	assert.Equal(t, "Guide", result.Metadata["title"])
+}
+
+func TestRenderTextLooseList(t *testing.T) {
+	text, _, err := newParser(false).RenderText(t.Context(), []byte("1. first\n\n   more\n2. second\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "1. first\n\n   more\n\n2. second\n", text)
+}
+
+// ── Resource URIs ────────────────────────────────────────────────
+
+func TestResourceURIParse(t *testing.T) {
+	for raw, want := range map[string]resource.URI{
+		"markdown://docs/guide.md":                            {Root: "docs", Path: "guide.md", Format: "html"},
+		"markdown://docs/a/b%20c.md?format=text":              {Root: "docs", Path: "a/b c.md", Format: "text"},
+		"markdown://docs/guide.md?format=application/json":    {Root: "docs", Path: "guide.md", Format: "outline"},
+		"markdown://docs/guide.md?format=text/plain%3B+q%3D1": {Root: "docs", Path: "guide.md", Format: "text"},
+	} {
+		u, err := resource.Parse(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, u, raw)
+	}
+
+	for _, raw := range []string{
+		"file://docs/guide.md",
+		"markdown:///guide.md",
+		"markdown://docs",
+		"markdown://docs/",
+		"markdown://user@docs/guide.md",
+		"markdown://docs:1/guide.md",
+		"markdown://docs/guide.md#top",
+		"markdown://docs/guide.md?format=pdf",
+		"markdown://docs/guide.md?format=text/plain;q=1",
+	} {
+		_, err := resource.Parse(raw)
+		assert.ErrorIs(t, err, resource.ErrInvalidURI, raw)
+	}
+}
+
+func TestResourceURIString(t *testing.T) {
+	assert.Equal(t, "markdown://docs/a/b%20c.md", resource.URI{Root: "docs", Path: "a/b c.md", Format: "html"}.String())
+	assert.Equal(t, "markdown://docs/guide.md?format=outline", resource.URI{Root: "docs", Path: "guide.md", Format: "outline"}.String())
+	assert.Equal(t, "application/json", resource.MimeType(resource.FormatOutline))
+}
+
+// ── Listing ──────────────────────────────────────────────────────
+
+func TestWorkspaceList(t *testing.T) {
+	ws := newWorkspace(t)
+	for name, content := range map[string]string{
+		"README.MD":       "# Readme\n",
+		"notes.txt":       "text",
+		"big.md":          string(make([]byte, 2048)),
+		".hidden.md":      "hidden",
+		".git/HEAD.md":    "hidden",
+		"docs/api/ref.md": "# Ref\n",
+		"docs/z.markdown": "z",
+	} {
+		path := filepath.Join(ws.Root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	require.NoError(t, os.Symlink(filepath.Join(ws.Root, "docs", "guide.md"), filepath.Join(ws.Root, "link.md")))
+
+	entries, err := ws.List(0)
+	require.NoError(t, err)
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"README.MD", "docs/api/ref.md", "docs/guide.md", "docs/z.markdown"}, paths)
+	assert.Equal(t, int64(8), entries[2].Size)
+
+	entries, err = ws.List(2)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
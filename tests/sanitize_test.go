@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Inline Styles ────────────────────────────────────────────────
+
+func TestSanitizeStyleDroppedByDefault(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	result := s.Sanitize(`<span style="color: red">x</span>`)
+
+	assert.NotContains(t, result, "style")
+	assert.Contains(t, result, "x")
+}
+
+func TestSanitizeStyleKeepsSafeProperties(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{AllowStyles: true})
+
+	result := s.Sanitize(`<p style="text-align: center; color: rgb(1, 2, 3); position: fixed; top: 0">x</p>`)
+
+	assert.Contains(t, result, "text-align: center")
+	assert.Contains(t, result, "color: rgb(1, 2, 3)")
+	assert.NotContains(t, result, "position")
+	assert.NotContains(t, result, "top")
+}
+
+func TestSanitizeStyleRejectsDangerousValues(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{AllowStyles: true})
+
+	result := s.Sanitize(`<p style="background-color: url(javascript:alert(1)); width: expression(alert(1)); color: \65 xpression(1)">x</p>`)
+
+	assert.Equal(t, "<p>x</p>", result)
+}
+
+func TestSanitizeStyleCustomProperties(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{
+		AllowStyles:     true,
+		StyleProperties: []string{"margin"},
+	})
+
+	result := s.Sanitize(`<div style="margin: 4px; color: red">x</div>`)
+
+	assert.Contains(t, result, `style="margin: 4px"`)
+	assert.NotContains(t, result, "color")
+}
+
+// ── Link Policy ──────────────────────────────────────────────────
+
+func newLinkSanitizer(targetBlank bool) *parser.HTMLSanitizer {
+	return parser.NewSanitizerWithPolicy(types.SanitizePolicy{
+		Links: types.LinkPolicy{
+			HardenExternal: true,
+			TargetBlank:    targetBlank,
+			TrustedHosts:   []string{"orchestra.dev", "*.example.com"},
+		},
+	})
+}
+
+func TestSanitizeExternalLinkHardened(t *testing.T) {
+	s := newLinkSanitizer(true)
+
+	result := s.Sanitize(`<a href="https://evil.test/x" rel="opener" target="_self">x</a>`)
+
+	assert.Contains(t, result, `rel="nofollow noopener noreferrer"`)
+	assert.Contains(t, result, `target="_blank"`)
+	assert.NotContains(t, result, "opener\"")
+}
+
+func TestSanitizeTrustedLinksUnchanged(t *testing.T) {
+	s := newLinkSanitizer(true)
+
+	for _, href := range []string{
+		"https://orchestra.dev/docs",
+		"https://docs.example.com/",
+		"/relative/path",
+		"#section",
+		"mailto:team@orchestra.dev",
+	} {
+		result := s.Sanitize(`<a href="` + href + `">x</a>`)
+		assert.NotContains(t, result, "rel=", href)
+		assert.NotContains(t, result, "target=", href)
+	}
+}
+
+func TestServiceHardensMarkdownLinks(t *testing.T) {
+	p := parser.New(types.RenderOptions{
+		Sanitize: types.SanitizePolicy{Links: types.LinkPolicy{HardenExternal: true}},
+	})
+	svc := service.New(p, true, 0)
+
+	html, err := svc.RenderString("[a](https://a.test) and <a href=\"https://b.test\">b</a>\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(html, `rel="nofollow noopener noreferrer"`))
+	assert.NotContains(t, html, "target=")
+}
+
+func TestLinksHardenedWithoutSanitizing(t *testing.T) {
+	p := parser.New(types.RenderOptions{
+		Sanitize: types.SanitizePolicy{Links: types.LinkPolicy{
+			HardenExternal: true,
+			TargetBlank:    true,
+			TrustedHosts:   []string{"orchestra.dev"},
+		}},
+	})
+	svc := service.New(p, false, 0)
+
+	html, err := svc.RenderString("[a](https://a.test) <https://b.test> [docs](https://orchestra.dev/x) [top](#top)\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(html, `rel="nofollow noopener noreferrer" target="_blank"`))
+	assert.Contains(t, html, `<a href="https://orchestra.dev/x">`)
+	assert.Contains(t, html, `<a href="#top">`)
+}
+
+// ── ID Prefixing ─────────────────────────────────────────────────
+
+func TestSanitizeIDPrefix(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{IDPrefix: "user-content-"})
+
+	result := s.Sanitize(`<p id="location">x</p><img name="cookie" src="a.png"><a href="#location">y</a><a href="#user-content-done">z</a>`)
+
+	assert.Contains(t, result, `id="user-content-location"`)
+	assert.Contains(t, result, `name="user-content-cookie"`)
+	assert.Contains(t, result, `href="#user-content-location"`)
+	assert.Contains(t, result, `href="#user-content-done"`)
+}
+
+func TestSanitizeNameDroppedWithoutPrefix(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	result := s.Sanitize(`<img name="cookie" id="x" src="a.png">`)
+
+	assert.NotContains(t, result, "name=")
+	assert.Contains(t, result, `id="x"`)
+}
+
+func TestServiceIDPrefixMatchesTOC(t *testing.T) {
+	p := parser.New(types.RenderOptions{
+		EnableTOC: true,
+		Sanitize:  types.SanitizePolicy{IDPrefix: "user-content-"},
+	})
+	svc := service.New(p, true, 0)
+
+	result, err := svc.Render(types.RenderRequest{Content: "# Cookie\n\n[top](#cookie)\n"})
+	require.NoError(t, err)
+
+	require.Len(t, result.TOC, 1)
+	assert.Equal(t, "user-content-cookie", result.TOC[0].ID)
+	assert.Contains(t, result.HTML, `id="user-content-cookie"`)
+	assert.Contains(t, result.HTML, `href="#user-content-cookie"`)
+}
+
+func TestIDPrefixWithoutSanitizing(t *testing.T) {
+	p := parser.New(types.RenderOptions{
+		EnableTOC: true,
+		Sanitize:  types.SanitizePolicy{IDPrefix: "user-content-"},
+	})
+	svc := service.New(p, false, 0)
+
+	result, err := svc.Render(types.RenderRequest{Content: "# Cookie\n\n[top](#cookie) [done](#user-content-done)\n"})
+	require.NoError(t, err)
+
+	require.Len(t, result.TOC, 1)
+	assert.Equal(t, "user-content-cookie", result.TOC[0].ID)
+	assert.Contains(t, result.HTML, `id="user-content-cookie"`)
+	assert.Contains(t, result.HTML, `href="#user-content-cookie"`)
+	assert.Contains(t, result.HTML, `href="#user-content-done"`)
+}
+
+// ── SVG and MathML ───────────────────────────────────────────────
+
+func TestSanitizeKeepsSVG(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	result := s.Sanitize(`<svg viewBox="0 0 10 10" width="10"><defs><linearGradient id="g"><stop offset="0" stop-color="red"></stop></linearGradient></defs><rect x="1" y="1" width="8" height="8" fill="url(#g)" onclick="alert(1)"></rect></svg>`)
+
+	assert.Contains(t, result, `<svg viewBox="0 0 10 10" width="10">`)
+	assert.Contains(t, result, `<linearGradient id="g">`)
+	assert.Contains(t, result, `fill="url(#g)"`)
+	assert.NotContains(t, result, "onclick")
+}
+
+func TestSanitizeBlocksScriptableSVG(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	result := s.Sanitize(`<svg><foreignObject><p>inner</p></foreignObject><script>alert(1)</script>` +
+		`<use href="https://evil.test/sprite.svg#x"></use><use xlink:href="javascript:alert(1)"></use>` +
+		`<a href="javascript:alert(1)"><text>t</text></a><animate attributeName="href" to="javascript:alert(1)"></animate>` +
+		`<rect fill="url(https://evil.test/x)"></rect></svg>`)
+
+	assert.NotContains(t, result, "foreignObject")
+	assert.NotContains(t, result, "inner")
+	assert.NotContains(t, result, "alert")
+	assert.NotContains(t, result, "evil.test")
+	assert.NotContains(t, result, "animate")
+	assert.Contains(t, result, "<text>t</text>")
+}
+
+func TestSanitizeSVGLocalReferencesPrefixed(t *testing.T) {
+	s := parser.NewSanitizerWithPolicy(types.SanitizePolicy{IDPrefix: "user-content-"})
+
+	result := s.Sanitize(`<svg><symbol id="icon"></symbol><use href="#icon"></use><rect fill="url(#icon)"></rect></svg>`)
+
+	assert.Contains(t, result, `id="user-content-icon"`)
+	assert.Contains(t, result, `href="#user-content-icon"`)
+	assert.Contains(t, result, `fill="url(#user-content-icon)"`)
+}
+
+func TestSanitizeKeepsMathML(t *testing.T) {
+	s := parser.NewSanitizer()
+
+	result := s.Sanitize(`<math display="block" href="javascript:alert(1)"><mfrac><mi>a</mi><mn>2</mn></mfrac>` +
+		`<semantics><mi>x</mi><annotation-xml encoding="text/html"><img src=x onerror=alert(1)></annotation-xml></semantics></math>`)
+
+	assert.Contains(t, result, `<math display="block">`)
+	assert.Contains(t, result, "<mfrac><mi>a</mi><mn>2</mn></mfrac>")
+	assert.NotContains(t, result, "href")
+	assert.NotContains(t, result, "annotation-xml")
+	assert.NotContains(t, result, "onerror")
+}
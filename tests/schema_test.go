@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/cache"
+	"github.com/orchestra-mcp/markdown/src/schema"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+// jsonFields returns the JSON names of t's fields, following embedded
+// structs.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			names = append(names, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+// decoded round-trips v through JSON the way tool arguments arrive.
+func decoded(t *testing.T, v any) any {
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out any
+	require.NoError(t, json.Unmarshal(raw, &out))
+	return out
+}
+
+// ── Schemas ──────────────────────────────────────────────────────
+
+func TestSchemaCoversEveryField(t *testing.T) {
+	cases := map[reflect.Type]map[string]any{
+		reflect.TypeFor[types.RenderOptions]():   schema.RenderOptions(),
+		reflect.TypeFor[types.SanitizePolicy]():  schema.SanitizePolicy(),
+		reflect.TypeFor[types.Limits]():          schema.Limits(),
+		reflect.TypeFor[types.RenderResult]():    schema.RenderResult(),
+		reflect.TypeFor[types.TOCEntry]():        schema.TOCEntry(),
+		reflect.TypeFor[types.CodeBlock]():       schema.CodeBlock(),
+		reflect.TypeFor[types.Truncation]():      schema.Truncation(),
+		reflect.TypeFor[types.Diagnostic]():      schema.Diagnostic(),
+		reflect.TypeFor[types.BlockRef]():        schema.BlockRef(),
+		reflect.TypeFor[types.BlockOp]():         schema.BlockOp(),
+		reflect.TypeFor[types.BatchItemResult](): schema.BatchItemResult(),
+		reflect.TypeFor[types.RenderRequest]():   schema.RenderRequest(),
+		reflect.TypeFor[types.BatchItem]():       schema.BatchItem(),
+		reflect.TypeFor[types.FileInfo]():        schema.FileInfo(),
+		reflect.TypeFor[types.BlockPatch]():      schema.BlockPatch(),
+		reflect.TypeFor[types.StreamUpdate]():    schema.StreamUpdate(),
+		reflect.TypeFor[cache.Stats]():           schema.CacheStats(),
+	}
+	for typ, s := range cases {
+		props := s["properties"].(map[string]any)
+		assert.ElementsMatch(t, jsonFields(typ), slices.Collect(maps.Keys(props)), typ.Name())
+	}
+}
+
+func TestSchemaMatchesRenderedResult(t *testing.T) {
+	svc := service.New(newParser(false), true, 0)
+	md := "---\ntitle: x\n---\n# A\n\n# A\n\n```go\nx := 1\n```\n\n[b][missing]\n"
+
+	result, err := svc.Render(types.RenderRequest{Content: md, Options: types.RenderOptions{RawHTML: types.RawHTMLEscape}})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Warnings)
+	assert.NoError(t, schema.Validate(schema.RenderResult(), decoded(t, result)))
+
+	result, err = svc.Render(types.RenderRequest{Content: md, Format: types.FormatBlocks})
+	require.NoError(t, err)
+	assert.NoError(t, schema.Validate(schema.RenderResult(), decoded(t, result)))
+}
+
+func TestSchemaFormatsAreSupported(t *testing.T) {
+	svc := service.New(newParser(false), false, 0)
+	for _, format := range schema.Formats {
+		_, err := svc.Render(types.RenderRequest{Content: "x", Format: format})
+		assert.NoError(t, err, format)
+	}
+}
+
+// ── Validation ───────────────────────────────────────────────────
+
+func TestSchemaValidateErrors(t *testing.T) {
+	s := schema.Object("", map[string]any{
+		"content": schema.String("Markdown"),
+		"format":  schema.Enum("Format", schema.Formats...),
+		"options": schema.RenderOptions(),
+		"items":   schema.Array("Items", schema.Object("", map[string]any{"id": schema.String("ID")}, "id")),
+	}, "content")
+
+	err := schema.Validate(s, decoded(t, map[string]any{
+		"format": "pdf",
+		"extra":  true,
+		"options": map[string]any{
+			"enable_toc": "yes",
+			"code_theme": "no-such-theme",
+			"limits":     map[string]any{"max_nodes": -1, "max_links": 1.5},
+		},
+		"items": []any{map[string]any{"id": "a"}, map[string]any{}},
+	}))
+	require.Error(t, err)
+
+	var ve *schema.ValidationError
+	require.ErrorAs(t, err, &ve)
+	paths := make(map[string]string)
+	for _, fe := range ve.Errors {
+		paths[fe.Path] = fe.Message
+	}
+	assert.Equal(t, map[string]string{
+		"content":                  "is required",
+		"extra":                    "is not a known property",
+		"format":                   "must be one of html, text, blocks, document",
+		"options.enable_toc":       "must be a boolean, got string",
+		"options.code_theme":       `"no-such-theme" is not an allowed value`,
+		"options.limits.max_nodes": "must be at least 0",
+		"options.limits.max_links": "must be an integer, got number",
+		"items[1].id":              "is required",
+	}, paths)
+}
+
+func TestSchemaValidateAccepts(t *testing.T) {
+	err := schema.Validate(schema.RenderOptions(), decoded(t, types.RenderOptions{
+		EnableTOC: true,
+		CodeTheme: "dracula",
+		RawHTML:   types.RawHTMLDrop,
+		Sanitize: types.SanitizePolicy{
+			Links: types.LinkPolicy{TrustedHosts: []string{"example.com"}},
+		},
+		Limits: types.Limits{MaxDepth: 10},
+	}))
+	assert.NoError(t, err)
+}
+
+func TestSchemaValidateAcceptsEmptyCodeTheme(t *testing.T) {
+	err := schema.Validate(schema.RenderOptions(), decoded(t, map[string]any{"code_theme": ""}))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Cancellation ─────────────────────────────────────────────────
+
+func TestRenderContextCancelled(t *testing.T) {
+	svc := newService()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.RenderContext(ctx, types.RenderRequest{Content: "# Hello\n"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = svc.ExtractTOCContext(ctx, "# Hello\n")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = svc.ExtractCodeBlocksContext(ctx, "```go\nx\n```\n")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// errAfter is a context whose Err reports cancellation once it has been
+// asked n times.
+type errAfter struct {
+	context.Context
+	n int
+}
+
+func (c *errAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestRenderChecksContextBetweenCodeBlocks(t *testing.T) {
+	// One top-level list holding two code blocks: the context is
+	// checked before and after parsing and once for the list, and
+	// then before each highlighted block.
+	md := []byte("- a\n\n  ```go\n  x := 1\n  ```\n\n  ```go\n  y := 2\n  ```\n")
+	p := newParser(false)
+
+	_, err := p.RenderBlocks(&errAfter{Context: context.Background(), n: 3}, md)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	blocks, err := p.RenderBlocks(&errAfter{Context: context.Background(), n: 5}, md)
+	require.NoError(t, err)
+	assert.Len(t, blocks.HTML, 1)
+}
+
+func TestExtractChecksContextBetweenMatches(t *testing.T) {
+	p := parser.New(types.RenderOptions{})
+	md := []byte("# A\n## B\n```go\nx\n```\n### C\n```\ny\n```\n")
+
+	toc, err := p.ExtractTOCContext(context.Background(), md)
+	require.NoError(t, err)
+	assert.Len(t, toc, 3)
+	_, err = p.ExtractTOCContext(&errAfter{Context: context.Background(), n: 2}, md)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	blocks, err := p.ExtractCodeBlocksContext(context.Background(), md)
+	require.NoError(t, err)
+	assert.Len(t, blocks, 2)
+	_, err = p.ExtractCodeBlocksContext(&errAfter{Context: context.Background(), n: 1}, md)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRenderTimeoutBudget(t *testing.T) {
+	svc := service.NewWithOptions(newParser(true), service.Options{
+		Sanitize:      true,
+		RenderTimeout: time.Nanosecond,
+	})
+	md := strings.Repeat("```go\nfmt.Println(\"hello\")\n```\n\n", 200)
+
+	_, err := svc.RenderContext(context.Background(), types.RenderRequest{Content: md})
+
+	var timeout *service.TimeoutError
+	require.True(t, errors.As(err, &timeout), "got %v", err)
+	assert.Equal(t, time.Nanosecond, timeout.Budget)
+}
+
+func TestRenderWithinBudget(t *testing.T) {
+	svc := service.NewWithOptions(newParser(true), service.Options{
+		Sanitize:      true,
+		RenderTimeout: time.Minute,
+	})
+
+	result, err := svc.RenderContext(context.Background(), types.RenderRequest{Content: "# Hello\n"})
+	require.NoError(t, err)
+	assert.Contains(t, result.HTML, "Hello")
+}
+
+// ── Batch Rendering ──────────────────────────────────────────────
+
+func TestRenderBatch(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{
+		Sanitize:     true,
+		MaxInputSize: 100,
+		BatchWorkers: 2,
+	})
+	items := []types.BatchItem{
+		{ID: "a", RenderRequest: types.RenderRequest{Content: "# A\n"}},
+		{ID: "big", RenderRequest: types.RenderRequest{Content: strings.Repeat("x", 101)}},
+		{ID: "bad", RenderRequest: types.RenderRequest{Content: "x", Options: types.RenderOptions{RawHTML: "maybe"}}},
+		{ID: "c", RenderRequest: types.RenderRequest{Content: "*C*\n"}},
+	}
+
+	results, err := svc.RenderBatch(context.Background(), items)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	for i, r := range results {
+		assert.Equal(t, i, r.Index)
+		assert.Equal(t, items[i].ID, r.ID)
+	}
+	require.NotNil(t, results[0].Result)
+	assert.Contains(t, results[0].Result.HTML, "<h1")
+	assert.Contains(t, results[1].Error, "exceeds maximum size")
+	assert.Nil(t, results[1].Result)
+	assert.NotEmpty(t, results[2].Error)
+	require.NotNil(t, results[3].Result)
+	assert.Contains(t, results[3].Result.HTML, "<em>C</em>")
+}
+
+func TestRenderBatchTooLarge(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{MaxBatchSize: 1})
+
+	_, err := svc.RenderBatch(context.Background(), make([]types.BatchItem, 2))
+	assert.Error(t, err)
+}
+
+// ── Streaming Writer ─────────────────────────────────────────────
+
+// slowReader returns at most n bytes per Read to exercise chunking.
+type slowReader struct {
+	r *strings.Reader
+	n int
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	return s.r.Read(p[:min(len(p), s.n)])
+}
+
+func TestRenderTo(t *testing.T) {
+	md := "# Report\n\n" + strings.Repeat("- line item\n", 3) + "\n```go\nx := 1\n\ny := 2\n```\n\nDone *now*"
+	var out strings.Builder
+
+	err := newService().RenderTo(context.Background(), &slowReader{strings.NewReader(md), 7}, &out, types.RenderOptions{})
+	require.NoError(t, err)
+
+	html := out.String()
+	assert.Contains(t, html, "<h1")
+	assert.Equal(t, 3, strings.Count(html, "<li>line item</li>"))
+	assert.Equal(t, 1, strings.Count(html, "<pre"))
+	assert.Contains(t, html, "<p>Done <em>now</em></p>")
+}
+
+func TestRenderToBoundsBlocks(t *testing.T) {
+	svc := service.New(newParser(false), true, 64)
+	md := strings.Repeat("short paragraph\n\n", 100) + strings.Repeat("x", 100)
+
+	var out strings.Builder
+	err := svc.RenderTo(context.Background(), strings.NewReader(md), &out, types.RenderOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, 100, strings.Count(out.String(), "<p>short paragraph</p>"))
+}
+
+func TestBlockRendererFailsOverLimitFirstBlock(t *testing.T) {
+	svc := service.New(newParser(false), true, 64)
+	br := svc.NewBlockRenderer(context.Background(), strings.NewReader(strings.Repeat("x", 100)), types.RenderOptions{})
+
+	_, err := br.Next()
+	require.Error(t, err)
+	assert.Equal(t, service.CodeInputTooLarge, service.AsError(err).Code)
+	_, again := br.Next()
+	assert.Equal(t, err, again)
+}
+
+func TestBlockRendererYieldsBlocksThenEOF(t *testing.T) {
+	svc := service.New(newParser(false), true, 0)
+	br := svc.NewBlockRenderer(context.Background(), strings.NewReader("# A\n\nbody\n"), types.RenderOptions{})
+
+	var blocks []string
+	for {
+		html, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		blocks = append(blocks, html)
+	}
+	require.Len(t, blocks, 2)
+	assert.Contains(t, blocks[0], "<h1")
+	assert.Contains(t, blocks[1], "<p>body</p>")
+}
+
+func TestRenderToBoundsTotalSize(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{MaxInputSize: 64, MaxStreamSize: 1000})
+	md := strings.Repeat("short paragraph\n\n", 1000)
+
+	var out strings.Builder
+	err := svc.RenderTo(context.Background(), &slowReader{strings.NewReader(md), 100}, &out, types.RenderOptions{})
+	require.Error(t, err)
+	e := service.AsError(err)
+	assert.Equal(t, service.CodeInputTooLarge, e.Code)
+	assert.Equal(t, 1000, e.Details["limit"])
+	assert.Less(t, strings.Count(out.String(), "<p>short paragraph</p>"), 1000/len("short paragraph\n\n")+1)
+}
+
+// delayReader sleeps before every read.
+type delayReader struct {
+	r io.Reader
+	d time.Duration
+}
+
+func (d *delayReader) Read(p []byte) (int, error) {
+	time.Sleep(d.d)
+	return d.r.Read(p)
+}
+
+func TestBlockRendererBoundsTotalTime(t *testing.T) {
+	svc := service.NewWithOptions(newParser(false), service.Options{RenderTimeout: time.Second, StreamTimeout: 50 * time.Millisecond})
+	md := strings.Repeat("short paragraph\n\n", 100)
+	br := svc.NewBlockRenderer(context.Background(), &delayReader{&slowReader{strings.NewReader(md), 17}, 5 * time.Millisecond}, types.RenderOptions{})
+
+	var err error
+	for err == nil {
+		_, err = br.Next()
+	}
+	require.NotErrorIs(t, err, io.EOF)
+	e := service.AsError(err)
+	assert.Equal(t, service.CodeTimeout, e.Code)
+	assert.EqualValues(t, 50, e.Details["budget_ms"])
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/orchestra-mcp/markdown/src/parser"
+	"github.com/orchestra-mcp/markdown/src/service"
+	"github.com/orchestra-mcp/markdown/src/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Partial Repair ───────────────────────────────────────────────
+
+func TestRepairPartial(t *testing.T) {
+	cases := map[string]string{
+		"```go\nfmt.Println(":         "```go\nfmt.Println(\n```\n",
+		"Hello **wor":                 "Hello **wor**\n",
+		"Hello **":                    "Hello\n",
+		"use `fmt.Pr":                 "use `fmt.Pr`\n",
+		"~~gone":                      "~~gone~~\n",
+		"see [docs](https://exa":      "see [docs](https://exa)\n",
+		"2 * 3 = 6":                   "2 * 3 = 6\n",
+		"* item one\n* item **two":    "* item one\n* item **two**\n",
+		"| a | b |":                   "| a | b |\n|---|---|\n",
+		"| a | b |\n|--":              "| a | b |\n|---|---|\n",
+		"| a | b |\n|---|---|\n| 1 |": "| a | b |\n|---|---|\n| 1 |\n",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, parser.RepairPartial(in), in)
+	}
+}
+
+func TestSplitStable(t *testing.T) {
+	src := "# T\n\npara\n\n```\ncode\n\nmore\n```\n\n- a\n\n  cont\n\nlast"
+	stable, tail := parser.SplitStable(src)
+
+	assert.Equal(t, []string{"# T\n", "para\n", "```\ncode\n\nmore\n```\n", "- a\n\n  cont\n"}, stable)
+	assert.Equal(t, "last", tail)
+}
+
+// ── Incremental Stream ───────────────────────────────────────────
+
+func TestStreamPatchesOnlyChangedBlocks(t *testing.T) {
+	st := newService().NewStream(types.RenderOptions{})
+	ctx := context.Background()
+
+	u, err := st.Append(ctx, "# Title\n\nSome **bo")
+	require.NoError(t, err)
+	require.Len(t, u.Patches, 2)
+	assert.True(t, u.Patches[0].Final)
+	assert.Contains(t, u.Patches[0].HTML, "Title")
+	assert.False(t, u.Patches[1].Final)
+	assert.Contains(t, u.Patches[1].HTML, "<strong>bo</strong>")
+	assert.Equal(t, 2, u.Blocks)
+
+	u, err = st.Append(ctx, "ld** text")
+	require.NoError(t, err)
+	require.Len(t, u.Patches, 1)
+	assert.Equal(t, 1, u.Patches[0].Index)
+	assert.Contains(t, u.Patches[0].HTML, "<strong>bold</strong> text")
+
+	u, err = st.Append(ctx, "\n\n```go\nx := 1")
+	require.NoError(t, err)
+	require.Len(t, u.Patches, 2)
+	assert.True(t, u.Patches[0].Final)
+	assert.Equal(t, 2, u.Patches[1].Index)
+	assert.Contains(t, u.Patches[1].HTML, "<pre")
+	assert.Equal(t, 3, u.Blocks)
+
+	u, err = st.Close(ctx)
+	require.NoError(t, err)
+	assert.True(t, u.Done)
+	require.Len(t, u.Patches, 1)
+	assert.True(t, u.Patches[0].Final)
+
+	_, err = st.Append(ctx, "more")
+	assert.Error(t, err)
+
+	snap := st.Snapshot()
+	assert.Len(t, snap.Patches, 3)
+	assert.Equal(t, 3, snap.Blocks)
+}
+
+func TestStreamFailedAppendLeavesStateUnchanged(t *testing.T) {
+	// The second block's fence renderer cancels the context, so the
+	// Append fails after the first block has rendered.
+	ctx, cancel := context.WithCancel(context.Background())
+	reg := parser.NewRegistry()
+	require.NoError(t, reg.RegisterFence("cancel", func(parser.FenceBlock) (string, error) {
+		cancel()
+		return "<div>fence</div>", nil
+	}, 0))
+	svc := service.New(parser.NewWithRegistry(types.RenderOptions{}, reg), false, 0)
+	md := "# One\n\n```cancel\nx\n```\n\nthree\n\nfour"
+
+	st := svc.NewStream(types.RenderOptions{})
+	_, err := st.Append(ctx, md)
+	require.ErrorIs(t, err, context.Canceled)
+
+	update, err := st.Append(context.Background(), md)
+	require.NoError(t, err)
+	assert.Equal(t, 4, update.Blocks)
+	for i, patch := range update.Patches {
+		assert.Equal(t, i, patch.Index)
+	}
+	assert.Len(t, update.Patches, 4)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/orchestra-mcp/markdown/src/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ── Helpers ──────────────────────────────────────────────────────
+
+// newWorkspace creates a root holding docs/guide.md and a secret file
+// beside the root.
+func newWorkspace(t *testing.T) *workspace.Workspace {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "guide.md"), []byte("# Guide\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.md"), []byte("secret"), 0o644))
+	return &workspace.Workspace{Root: root, MaxSize: 1024, Extensions: []string{".md", ".markdown"}}
+}
+
+// ── Reading ──────────────────────────────────────────────────────
+
+func TestWorkspaceRead(t *testing.T) {
+	ws := newWorkspace(t)
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(ws.Root, "docs", "guide.md"), mtime, mtime))
+
+	f, err := ws.Read("docs/../docs/guide.md")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("# Guide\n"))
+	assert.Equal(t, "# Guide\n", string(f.Content))
+	assert.Equal(t, "docs/guide.md", f.Info.Path)
+	assert.Equal(t, int64(8), f.Info.Size)
+	assert.Equal(t, mtime, f.Info.ModTime)
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Info.Hash)
+}
+
+func TestWorkspaceRejectsEscapes(t *testing.T) {
+	ws := newWorkspace(t)
+	require.NoError(t, os.Symlink(filepath.Join(ws.Root, "..", "secret.md"), filepath.Join(ws.Root, "link.md")))
+	require.NoError(t, os.Symlink("..", filepath.Join(ws.Root, "up")))
+
+	for _, path := range []string{
+		"../secret.md",
+		"docs/../../secret.md",
+		"/etc/passwd.md",
+		filepath.Join(ws.Root, "docs", "guide.md"),
+		`docs\..\..\secret.md`,
+		"link.md",
+		"up/secret.md",
+		"",
+	} {
+		_, err := ws.Read(path)
+		assert.ErrorIs(t, err, workspace.ErrInvalidPath, path)
+	}
+}
+
+func TestWorkspaceRejectsFiles(t *testing.T) {
+	ws := newWorkspace(t)
+	require.NoError(t, os.WriteFile(filepath.Join(ws.Root, ".env"), []byte("TOKEN=x"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(ws.Root, "big.md"), []byte(strings.Repeat("x", 2048)), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(ws.Root, "dir.md"), 0o755))
+	require.NoError(t, syscall.Mkfifo(filepath.Join(ws.Root, "pipe.md"), 0o644))
+
+	for _, path := range []string{".env", "dir.md", "pipe.md"} {
+		_, err := ws.Read(path)
+		assert.ErrorIs(t, err, workspace.ErrInvalidPath, path)
+	}
+
+	_, err := ws.Read("missing.md")
+	assert.ErrorIs(t, err, workspace.ErrNotFound)
+
+	_, err = ws.Read("big.md")
+	var size *workspace.SizeError
+	require.ErrorAs(t, err, &size)
+	assert.Equal(t, int64(1024), size.Limit)
+	assert.Equal(t, int64(2048), size.Size)
+
+	_, err = (&workspace.Workspace{}).Read("docs/guide.md")
+	assert.ErrorIs(t, err, workspace.ErrDisabled)
+}